@@ -0,0 +1,143 @@
+package core
+
+import (
+	"strings"
+
+	"github.com/candbright/go-server/internal/mc-server/core/model"
+	"github.com/candbright/go-server/pkg/dw"
+	"github.com/pkg/errors"
+)
+
+// AllowListAdd 添加或更新白名单玩家。
+// 服务器运行且不需要设置 ignoresPlayerLimit 时使用 allowlist add 命令；
+// 否则直接修改 allowlist.json，运行中的服务器随后执行 allowlist reload 使其生效。
+func (server *Server) AllowListAdd(user model.AllowListUser) error {
+	user.Name = strings.TrimSpace(user.Name)
+	if user.Name == "" {
+		return errors.New("username is required")
+	}
+	if server.Active() && !user.IgnoresPlayerLimit && user.XUid == "" {
+		return server.execCmd("allowlist", "add", quoteTarget(user.Name))
+	}
+	_, err := server.AllowListImport(model.AllowList{user}, false)
+	return err
+}
+
+// AllowListDelete 移除白名单玩家，服务器停止时直接修改 allowlist.json
+func (server *Server) AllowListDelete(username string) error {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return errors.New("username is required")
+	}
+	if server.Active() {
+		return server.execCmd("allowlist", "remove", quoteTarget(username))
+	}
+	return server.editAllowList(func(allowList model.AllowList) (model.AllowList, error) {
+		result := make(model.AllowList, 0, len(allowList))
+		for _, user := range allowList {
+			if !strings.EqualFold(user.Name, username) {
+				result = append(result, user)
+			}
+		}
+		if len(result) == len(allowList) {
+			return nil, errors.Errorf("player [%s] is not in allowlist", username)
+		}
+		return result, nil
+	})
+}
+
+// AllowListImport 批量添加或更新白名单玩家，replace 为 true 时以 users 替换整个白名单。
+// 直接修改 allowlist.json，运行中的服务器随后执行 allowlist reload，返回新增的玩家数
+func (server *Server) AllowListImport(users model.AllowList, replace bool) (int, error) {
+	added := 0
+	err := server.editAllowList(func(allowList model.AllowList) (model.AllowList, error) {
+		if replace {
+			allowList = model.AllowList{}
+		}
+		added = 0
+		for _, user := range users {
+			merged := false
+			for i := range allowList {
+				if strings.EqualFold(allowList[i].Name, user.Name) {
+					if user.XUid == "" {
+						user.XUid = allowList[i].XUid
+					}
+					allowList[i] = user
+					merged = true
+					break
+				}
+			}
+			if !merged {
+				allowList = append(allowList, user)
+				added++
+			}
+		}
+		return allowList, nil
+	})
+	return added, err
+}
+
+// AllowListRemove 从 allowlist.json 中批量移除玩家，不在白名单中的玩家被忽略，运行中的服务器随后执行 allowlist reload
+func (server *Server) AllowListRemove(usernames ...string) error {
+	return server.editAllowList(func(allowList model.AllowList) (model.AllowList, error) {
+		result := make(model.AllowList, 0, len(allowList))
+		for _, user := range allowList {
+			removed := false
+			for _, username := range usernames {
+				if strings.EqualFold(user.Name, username) {
+					removed = true
+					break
+				}
+			}
+			if !removed {
+				result = append(result, user)
+			}
+		}
+		return result, nil
+	})
+}
+
+func (server *Server) AllowListOn() error {
+	return server.execCmd("allowlist", "on")
+}
+
+func (server *Server) AllowListOff() error {
+	return server.execCmd("allowlist", "off")
+}
+
+// AllowListReload 让运行中的服务器重新读取 allowlist.json
+func (server *Server) AllowListReload() error {
+	return server.execCmd("allowlist", "reload")
+}
+
+func (server *Server) GetAllowList() (model.AllowList, error) {
+	w, err := dw.Default[model.AllowList](server.AllowListFilePath())
+	if err != nil {
+		return nil, err
+	}
+	return w.Data, nil
+}
+
+// editAllowList 修改磁盘上的 allowlist.json，服务器运行时修改后执行 allowlist reload
+func (server *Server) editAllowList(edit func(allowList model.AllowList) (model.AllowList, error)) error {
+	if !server.ServerExist() {
+		return errors.New("server not exist")
+	}
+	w, err := dw.JsonOrDefault[model.AllowList](server.AllowListFilePath(), model.AllowList{})
+	if err != nil {
+		return err
+	}
+	allowList, err := edit(w.Data)
+	if err != nil {
+		return err
+	}
+	w.Data = allowList
+	err = w.Write()
+	if err != nil {
+		return err
+	}
+	if server.Active() {
+		return server.AllowListReload()
+	}
+	return nil
+}
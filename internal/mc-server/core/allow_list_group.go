@@ -0,0 +1,232 @@
+package core
+
+import (
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/candbright/go-server/internal/mc-server/core/model"
+	"github.com/candbright/go-server/pkg/dw"
+	"github.com/pkg/errors"
+)
+
+// AllowListGroup 可被多个服务器共用的白名单分组，分组中的玩家会同步到所有关联的服务器
+type AllowListGroup struct {
+	Name    string          `json:"name"`
+	Users   model.AllowList `json:"users"`
+	Servers []string        `json:"servers"`
+}
+
+// SyncResult 将分组变更同步到各服务器的结果，key 为服务器 id，value 为失败原因
+type SyncResult map[string]string
+
+// AllowListGroups 持久化所有白名单分组
+type AllowListGroups struct {
+	mu sync.Mutex
+	w  *dw.DataWriter[map[string]*AllowListGroup]
+}
+
+func NewAllowListGroups(file string) (*AllowListGroups, error) {
+	w, err := dw.JsonOrDefault[map[string]*AllowListGroup](file, make(map[string]*AllowListGroup))
+	if err != nil {
+		return nil, err
+	}
+	return &AllowListGroups{w: w}, nil
+}
+
+func (manager *ServerManager) AllowListGroupsFilePath() string {
+	return path.Join(manager.rootDir, "allowlist_groups.json")
+}
+
+func (manager *ServerManager) allowListGroups() (*AllowListGroups, error) {
+	manager.groupsOnce.Do(func() {
+		manager.groups, manager.groupsErr = NewAllowListGroups(manager.AllowListGroupsFilePath())
+	})
+	return manager.groups, manager.groupsErr
+}
+
+// ListAllowListGroups 返回所有分组，按名称排序
+func (manager *ServerManager) ListAllowListGroups() ([]AllowListGroup, error) {
+	groups, err := manager.allowListGroups()
+	if err != nil {
+		return nil, err
+	}
+	groups.mu.Lock()
+	defer groups.mu.Unlock()
+	result := make([]AllowListGroup, 0, len(groups.w.Data))
+	for _, group := range groups.w.Data {
+		result = append(result, *group)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+func (manager *ServerManager) CreateAllowListGroup(name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("group name is required")
+	}
+	return manager.editAllowListGroups(func(data map[string]*AllowListGroup) error {
+		if _, ok := data[name]; ok {
+			return errors.Errorf("group [%s] already exists", name)
+		}
+		data[name] = &AllowListGroup{Name: name, Users: model.AllowList{}, Servers: []string{}}
+		return nil
+	})
+}
+
+// DeleteAllowListGroup 删除分组，已同步到服务器的玩家保留在各服务器的白名单中
+func (manager *ServerManager) DeleteAllowListGroup(name string) error {
+	return manager.editAllowListGroups(func(data map[string]*AllowListGroup) error {
+		if _, ok := data[name]; !ok {
+			return errors.Errorf("group [%s] not found", name)
+		}
+		delete(data, name)
+		return nil
+	})
+}
+
+// AttachAllowListGroup 将分组关联到服务器，并把分组中的玩家同步到该服务器
+func (manager *ServerManager) AttachAllowListGroup(name, serverID string) (SyncResult, error) {
+	server, err := manager.GetServer(serverID)
+	if err != nil {
+		return nil, err
+	}
+	var users model.AllowList
+	err = manager.editAllowListGroup(name, func(group *AllowListGroup) error {
+		for _, id := range group.Servers {
+			if id == serverID {
+				return errors.Errorf("group [%s] is already attached to server [%s]", name, serverID)
+			}
+		}
+		group.Servers = append(group.Servers, serverID)
+		users = group.Users
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := SyncResult{}
+	if len(users) > 0 {
+		if _, err = server.AllowListImport(users, false); err != nil {
+			result[serverID] = err.Error()
+		}
+	}
+	return result, nil
+}
+
+// DetachAllowListGroup 取消分组与服务器的关联，已同步的玩家保留在服务器白名单中
+func (manager *ServerManager) DetachAllowListGroup(name, serverID string) error {
+	return manager.editAllowListGroup(name, func(group *AllowListGroup) error {
+		servers := make([]string, 0, len(group.Servers))
+		for _, id := range group.Servers {
+			if id != serverID {
+				servers = append(servers, id)
+			}
+		}
+		if len(servers) == len(group.Servers) {
+			return errors.Errorf("group [%s] is not attached to server [%s]", name, serverID)
+		}
+		group.Servers = servers
+		return nil
+	})
+}
+
+// AllowListGroupAdd 向分组添加或更新玩家，并同步到所有关联的服务器。已有同名玩家时合并，新记录的 xuid 为空则沿用原值
+func (manager *ServerManager) AllowListGroupAdd(name string, user model.AllowListUser) (SyncResult, error) {
+	user.Name = strings.TrimSpace(user.Name)
+	if user.Name == "" {
+		return nil, errors.New("username is required")
+	}
+	var servers []string
+	err := manager.editAllowListGroup(name, func(group *AllowListGroup) error {
+		for i := range group.Users {
+			if strings.EqualFold(group.Users[i].Name, user.Name) {
+				// 只提供玩家名时保留已知的 xuid
+				if user.XUid == "" {
+					user.XUid = group.Users[i].XUid
+				}
+				group.Users[i] = user
+				servers = group.Servers
+				return nil
+			}
+		}
+		group.Users = append(group.Users, user)
+		servers = group.Servers
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return manager.syncAllowList(servers, func(server *Server) error {
+		_, err := server.AllowListImport(model.AllowList{user}, false)
+		return err
+	}), nil
+}
+
+// AllowListGroupRemove 从分组移除玩家，并从所有关联的服务器白名单中移除
+func (manager *ServerManager) AllowListGroupRemove(name, username string) (SyncResult, error) {
+	var servers []string
+	err := manager.editAllowListGroup(name, func(group *AllowListGroup) error {
+		users := make(model.AllowList, 0, len(group.Users))
+		for _, user := range group.Users {
+			if !strings.EqualFold(user.Name, username) {
+				users = append(users, user)
+			}
+		}
+		if len(users) == len(group.Users) {
+			return errors.Errorf("player [%s] is not in group [%s]", username, name)
+		}
+		group.Users = users
+		servers = group.Servers
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return manager.syncAllowList(servers, func(server *Server) error {
+		return server.AllowListRemove(username)
+	}), nil
+}
+
+// syncAllowList 对每个关联的服务器执行同步，单个服务器失败不影响其他服务器
+func (manager *ServerManager) syncAllowList(servers []string, sync func(server *Server) error) SyncResult {
+	result := SyncResult{}
+	for _, id := range servers {
+		server, err := manager.GetServer(id)
+		if err == nil {
+			err = sync(server)
+		}
+		if err != nil {
+			result[id] = err.Error()
+		}
+	}
+	return result
+}
+
+func (manager *ServerManager) editAllowListGroups(edit func(data map[string]*AllowListGroup) error) error {
+	groups, err := manager.allowListGroups()
+	if err != nil {
+		return err
+	}
+	groups.mu.Lock()
+	defer groups.mu.Unlock()
+	err = edit(groups.w.Data)
+	if err != nil {
+		return err
+	}
+	return groups.w.Write()
+}
+
+func (manager *ServerManager) editAllowListGroup(name string, edit func(group *AllowListGroup) error) error {
+	return manager.editAllowListGroups(func(data map[string]*AllowListGroup) error {
+		group, ok := data[name]
+		if !ok {
+			return errors.Errorf("group [%s] not found", name)
+		}
+		return edit(group)
+	})
+}
@@ -0,0 +1,121 @@
+package core
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/candbright/go-server/internal/mc-server/core/model"
+)
+
+// testManager 创建只包含指定服务器、不会自动加载的 ServerManager
+func testManager(t *testing.T, servers ...*Server) *ServerManager {
+	manager := &ServerManager{
+		rootDir:     t.TempDir(),
+		servers:     &sync.Map{},
+		downloaders: &sync.Map{},
+		cacheTTL:    time.Hour,
+		lastLoad:    time.Now(),
+	}
+	for _, server := range servers {
+		manager.servers.Store(server.id, server)
+	}
+	return manager
+}
+
+func TestServerManager_AllowListGroup(t *testing.T) {
+	server := testFakeServer(t)
+	manager := testManager(t, server)
+
+	err := manager.CreateAllowListGroup("friends")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err = manager.CreateAllowListGroup("friends"); err == nil {
+		t.Fatal("duplicate group should be rejected")
+	}
+	result, err := manager.AllowListGroupAdd("friends", model.AllowListUser{Name: "Steve", XUid: "100"})
+	if err != nil || len(result) != 0 {
+		t.Fatalf("%+v %v", err, result)
+	}
+	// 只按玩家名重新添加时保留已知的 xuid
+	if _, err = manager.AllowListGroupAdd("friends", model.AllowListUser{Name: "Steve", IgnoresPlayerLimit: true}); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	groups, err := manager.ListAllowListGroups()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if len(groups) != 1 || len(groups[0].Users) != 1 || groups[0].Users[0].XUid != "100" || !groups[0].Users[0].IgnoresPlayerLimit {
+		t.Fatalf("unexpected group after re-adding by name: %+v", groups)
+	}
+
+	// 关联时同步分组中已有的玩家
+	result, err = manager.AttachAllowListGroup("friends", server.id)
+	if err != nil || len(result) != 0 {
+		t.Fatalf("%+v %v", err, result)
+	}
+	if _, err = manager.AttachAllowListGroup("friends", server.id); err == nil {
+		t.Fatal("attaching twice should be rejected")
+	}
+	allowList, err := server.GetAllowList()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if len(allowList) != 1 || allowList[0].Name != "Steve" {
+		t.Fatalf("unexpected allowlist after attach: %+v", allowList)
+	}
+
+	// 新增的玩家传播到已关联的服务器
+	result, err = manager.AllowListGroupAdd("friends", model.AllowListUser{Name: "Alex"})
+	if err != nil || len(result) != 0 {
+		t.Fatalf("%+v %v", err, result)
+	}
+	allowList, _ = server.GetAllowList()
+	if len(allowList) != 2 {
+		t.Fatalf("unexpected allowlist after group add: %+v", allowList)
+	}
+
+	result, err = manager.AllowListGroupRemove("friends", "steve")
+	if err != nil || len(result) != 0 {
+		t.Fatalf("%+v %v", err, result)
+	}
+	allowList, _ = server.GetAllowList()
+	if len(allowList) != 1 || allowList[0].Name != "Alex" {
+		t.Fatalf("unexpected allowlist after group remove: %+v", allowList)
+	}
+
+	err = manager.DetachAllowListGroup("friends", server.id)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	groups, err = manager.ListAllowListGroups()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if len(groups) != 1 || len(groups[0].Servers) != 0 || len(groups[0].Users) != 1 {
+		t.Fatalf("unexpected groups: %+v", groups)
+	}
+}
+
+func TestServerManager_AllowListGroupSyncFailure(t *testing.T) {
+	server := testFakeServer(t)
+	manager := testManager(t, server)
+	err := manager.CreateAllowListGroup("friends")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	_, err = manager.AttachAllowListGroup("friends", server.id)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	// 服务器被移除后同步失败，但分组本身仍然更新
+	manager.servers.Delete(server.id)
+	result, err := manager.AllowListGroupAdd("friends", model.AllowListUser{Name: "Steve"})
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if _, ok := result[server.id]; !ok {
+		t.Fatalf("expected sync failure for %s, got %v", server.id, result)
+	}
+}
@@ -0,0 +1,96 @@
+package core
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/candbright/go-server/internal/mc-server/core/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	AllowListFormatJson = "json"
+	AllowListFormatCsv  = "csv"
+)
+
+// allowListCsvHeader CSV 导入导出的列，导入时表头可省略
+var allowListCsvHeader = []string{"name", "xuid", "ignoresPlayerLimit"}
+
+// ParseAllowList 解析 JSON（与 allowlist.json 格式相同）或 CSV 格式的白名单，忽略空用户名并按用户名去重
+func ParseAllowList(data []byte, format string) (model.AllowList, error) {
+	var users model.AllowList
+	switch format {
+	case AllowListFormatJson:
+		if err := json.Unmarshal(data, &users); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	case AllowListFormatCsv:
+		reader := csv.NewReader(bytes.NewReader(data))
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+		records, err := reader.ReadAll()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		for i, record := range records {
+			if i == 0 && len(record) > 0 && strings.EqualFold(strings.TrimSpace(record[0]), "name") {
+				continue
+			}
+			user := model.AllowListUser{Name: strings.TrimSpace(record[0])}
+			if len(record) > 1 {
+				user.XUid = strings.TrimSpace(record[1])
+			}
+			if len(record) > 2 && strings.TrimSpace(record[2]) != "" {
+				user.IgnoresPlayerLimit, err = strconv.ParseBool(strings.TrimSpace(record[2]))
+				if err != nil {
+					return nil, errors.Errorf("line %d: invalid ignoresPlayerLimit [%s]", i+1, record[2])
+				}
+			}
+			users = append(users, user)
+		}
+	default:
+		return nil, errors.Errorf("unsupported allowlist format [%s]", format)
+	}
+
+	result := make(model.AllowList, 0, len(users))
+	seen := make(map[string]int)
+	for _, user := range users {
+		user.Name = strings.TrimSpace(user.Name)
+		if user.Name == "" {
+			continue
+		}
+		key := strings.ToLower(user.Name)
+		if i, ok := seen[key]; ok {
+			result[i] = user
+			continue
+		}
+		seen[key] = len(result)
+		result = append(result, user)
+	}
+	return result, nil
+}
+
+// EncodeAllowList 将白名单编码为 JSON 或带表头的 CSV
+func EncodeAllowList(users model.AllowList, format string) ([]byte, error) {
+	switch format {
+	case AllowListFormatJson:
+		if users == nil {
+			users = model.AllowList{}
+		}
+		data, err := json.MarshalIndent(users, "", "  ")
+		return data, errors.WithStack(err)
+	case AllowListFormatCsv:
+		var buf bytes.Buffer
+		writer := csv.NewWriter(&buf)
+		_ = writer.Write(allowListCsvHeader)
+		for _, user := range users {
+			_ = writer.Write([]string{user.Name, user.XUid, strconv.FormatBool(user.IgnoresPlayerLimit)})
+		}
+		writer.Flush()
+		return buf.Bytes(), errors.WithStack(writer.Error())
+	}
+	return nil, errors.Errorf("unsupported allowlist format [%s]", format)
+}
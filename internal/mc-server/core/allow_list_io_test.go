@@ -0,0 +1,58 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/candbright/go-server/internal/mc-server/core/model"
+)
+
+func TestParseAllowList_Csv(t *testing.T) {
+	data := "name,xuid,ignoresPlayerLimit\nSteve,100,true\nAlex,,\nsteve,,false\n\n"
+	users, err := ParseAllowList([]byte(data), AllowListFormatCsv)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	// 重复的玩家名（不区分大小写）以后出现的为准
+	expected := model.AllowList{
+		{Name: "steve"},
+		{Name: "Alex"},
+	}
+	if len(users) != len(expected) || users[0] != expected[0] || users[1] != expected[1] {
+		t.Fatalf("unexpected users: %+v", users)
+	}
+
+	users, err = ParseAllowList([]byte("Steve\nAlex,200"), AllowListFormatCsv)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if len(users) != 2 || users[1].XUid != "200" {
+		t.Fatalf("unexpected users without header: %+v", users)
+	}
+
+	if _, err = ParseAllowList([]byte("Steve,100,maybe"), AllowListFormatCsv); err == nil {
+		t.Fatal("invalid ignoresPlayerLimit should be rejected")
+	}
+	if _, err = ParseAllowList([]byte("Steve"), "xml"); err == nil {
+		t.Fatal("unknown format should be rejected")
+	}
+}
+
+func TestEncodeAllowList_RoundTrip(t *testing.T) {
+	users := model.AllowList{
+		{Name: "Steve", XUid: "100", IgnoresPlayerLimit: true},
+		{Name: "Alex"},
+	}
+	for _, format := range []string{AllowListFormatJson, AllowListFormatCsv} {
+		data, err := EncodeAllowList(users, format)
+		if err != nil {
+			t.Fatalf("%s: %+v", format, err)
+		}
+		parsed, err := ParseAllowList(data, format)
+		if err != nil {
+			t.Fatalf("%s: %+v", format, err)
+		}
+		if len(parsed) != len(users) || parsed[0] != users[0] || parsed[1] != users[1] {
+			t.Fatalf("%s: unexpected round trip result: %+v", format, parsed)
+		}
+	}
+}
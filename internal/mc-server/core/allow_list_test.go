@@ -0,0 +1,94 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/candbright/go-server/internal/mc-server/core/model"
+)
+
+func TestServer_AllowListOffline(t *testing.T) {
+	server := testFakeServer(t)
+	err := server.AllowListAdd(model.AllowListUser{Name: "Steve"})
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	err = server.AllowListAdd(model.AllowListUser{Name: "Alex", XUid: "200", IgnoresPlayerLimit: true})
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	// 已存在的玩家更新设置，保留已知的 xuid
+	err = server.AllowListAdd(model.AllowListUser{Name: "alex", IgnoresPlayerLimit: false})
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	allowList, err := server.GetAllowList()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	expected := model.AllowList{
+		{Name: "Steve"},
+		{Name: "alex", XUid: "200"},
+	}
+	if len(allowList) != len(expected) || allowList[0] != expected[0] || allowList[1] != expected[1] {
+		t.Fatalf("unexpected allowlist: %+v", allowList)
+	}
+
+	err = server.AllowListDelete("STEVE")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err = server.AllowListDelete("Steve"); err == nil {
+		t.Fatal("deleting a missing player should fail")
+	}
+	if err = server.AllowListAdd(model.AllowListUser{Name: " "}); err == nil {
+		t.Fatal("empty username should be rejected")
+	}
+	allowList, _ = server.GetAllowList()
+	if len(allowList) != 1 {
+		t.Fatalf("unexpected allowlist after delete: %+v", allowList)
+	}
+}
+
+func TestServer_AllowListRunning(t *testing.T) {
+	server := testFakeServer(t)
+	err := server.Start()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	_, err = server.process.WaitReady(5 * time.Second)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	consoleHas := func(text string) bool {
+		for _, line := range server.Console().Lines(0) {
+			if line.Text == text {
+				return true
+			}
+		}
+		return false
+	}
+
+	err = server.AllowListAdd(model.AllowListUser{Name: "Steve"})
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if !consoleHas("[INFO] allowlist add Steve") {
+		t.Fatal("expected allowlist add command")
+	}
+	// ignoresPlayerLimit 只能写入文件，写入后通知服务器重新加载
+	err = server.AllowListAdd(model.AllowListUser{Name: "Alex", IgnoresPlayerLimit: true})
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if !consoleHas("[INFO] allowlist reload") {
+		t.Fatal("expected allowlist reload after editing file")
+	}
+	allowList, err := server.GetAllowList()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if len(allowList) != 1 || !allowList[0].IgnoresPlayerLimit {
+		t.Fatalf("unexpected allowlist: %+v", allowList)
+	}
+}
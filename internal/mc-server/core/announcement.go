@@ -0,0 +1,239 @@
+package core
+
+import (
+	"encoding/json"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/candbright/go-log/log"
+	"github.com/candbright/go-server/internal/mc-server/utils"
+	"github.com/candbright/go-server/pkg/dw"
+	"github.com/pkg/errors"
+)
+
+const (
+	BroadcastSay     = "say"
+	BroadcastTellraw = "tellraw"
+)
+
+// announceInterval 检查定时公告的间隔，需小于一分钟以免错过 cron 时间点
+const announceInterval = 20 * time.Second
+
+// Announcement 定时在游戏内轮播的公告，IntervalMinutes 与 Cron 二选一
+type Announcement struct {
+	ID       string   `json:"id"`
+	Messages []string `json:"messages"`
+	// Type 发送方式，say 或 tellraw，默认为 say
+	Type string `json:"type"`
+	// IntervalMinutes 服务器运行期间每隔多少分钟发送一次
+	IntervalMinutes int `json:"interval_minutes,omitempty"`
+	// Cron 按 cron 表达式（分 时 日 月 周）指定的时间发送
+	Cron    string `json:"cron,omitempty"`
+	Enabled bool   `json:"enabled"`
+	// NextIndex 下一条要发送的消息下标，轮播位置在面板重启后保留
+	NextIndex  int        `json:"next_index"`
+	LastSentAt *time.Time `json:"last_sent_at,omitempty"`
+}
+
+// Validate 校验公告配置并补全默认值
+func (announcement *Announcement) Validate() error {
+	messages := make([]string, 0, len(announcement.Messages))
+	for _, message := range announcement.Messages {
+		if message = strings.TrimSpace(message); message != "" {
+			messages = append(messages, message)
+		}
+	}
+	if len(messages) == 0 {
+		return errors.New("at least one message is required")
+	}
+	announcement.Messages = messages
+	if announcement.Type == "" {
+		announcement.Type = BroadcastSay
+	}
+	if announcement.Type != BroadcastSay && announcement.Type != BroadcastTellraw {
+		return errors.Errorf("unsupported broadcast type [%s]", announcement.Type)
+	}
+	if (announcement.IntervalMinutes > 0) == (announcement.Cron != "") {
+		return errors.New("exactly one of a positive interval_minutes and cron is required")
+	}
+	if announcement.Cron != "" {
+		if _, err := ParseCron(announcement.Cron); err != nil {
+			return err
+		}
+	}
+	if announcement.NextIndex >= len(announcement.Messages) || announcement.NextIndex < 0 {
+		announcement.NextIndex = 0
+	}
+	return nil
+}
+
+// due 判断公告在 now 是否应当发送，since 为服务器本次进入运行状态的时间
+func (announcement *Announcement) due(now, since time.Time) bool {
+	if !announcement.Enabled {
+		return false
+	}
+	if announcement.Cron != "" {
+		schedule, err := ParseCron(announcement.Cron)
+		if err != nil || !schedule.Matches(now) {
+			return false
+		}
+		// 同一分钟内只发送一次
+		return announcement.LastSentAt == nil || !announcement.LastSentAt.Truncate(time.Minute).Equal(now.Truncate(time.Minute))
+	}
+	last := since
+	if announcement.LastSentAt != nil && announcement.LastSentAt.After(last) {
+		last = *announcement.LastSentAt
+	}
+	return now.Sub(last) >= time.Duration(announcement.IntervalMinutes)*time.Minute
+}
+
+// AnnouncementList 持久化的服务器公告配置
+type AnnouncementList struct {
+	mu sync.Mutex
+	w  *dw.DataWriter[[]Announcement]
+}
+
+func NewAnnouncementList(file string) (*AnnouncementList, error) {
+	w, err := dw.JsonOrDefault[[]Announcement](file, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &AnnouncementList{w: w}, nil
+}
+
+func (list *AnnouncementList) List() []Announcement {
+	list.mu.Lock()
+	defer list.mu.Unlock()
+	return append([]Announcement{}, list.w.Data...)
+}
+
+// Add 添加公告并分配 id
+func (list *AnnouncementList) Add(announcement Announcement) (Announcement, error) {
+	if err := announcement.Validate(); err != nil {
+		return Announcement{}, err
+	}
+	announcement.ID = utils.RandomString(8, utils.AlphaNumCharset)
+	announcement.LastSentAt = nil
+	list.mu.Lock()
+	defer list.mu.Unlock()
+	list.w.Data = append(list.w.Data, announcement)
+	return announcement, list.w.Write()
+}
+
+// Update 按 id 替换公告配置，保留原有的发送记录和轮播位置
+func (list *AnnouncementList) Update(announcement Announcement) (Announcement, error) {
+	if err := announcement.Validate(); err != nil {
+		return Announcement{}, err
+	}
+	list.mu.Lock()
+	defer list.mu.Unlock()
+	for i := range list.w.Data {
+		if list.w.Data[i].ID == announcement.ID {
+			announcement.LastSentAt = list.w.Data[i].LastSentAt
+			announcement.NextIndex = list.w.Data[i].NextIndex % len(announcement.Messages)
+			list.w.Data[i] = announcement
+			return announcement, list.w.Write()
+		}
+	}
+	return Announcement{}, errors.Errorf("announcement [%s] not found", announcement.ID)
+}
+
+func (list *AnnouncementList) Delete(id string) error {
+	list.mu.Lock()
+	defer list.mu.Unlock()
+	for i := range list.w.Data {
+		if list.w.Data[i].ID == id {
+			list.w.Data = append(list.w.Data[:i], list.w.Data[i+1:]...)
+			return list.w.Write()
+		}
+	}
+	return errors.Errorf("announcement [%s] not found", id)
+}
+
+// pendingBroadcast 一条待发送的公告消息
+type pendingBroadcast struct {
+	id      string
+	kind    string
+	message string
+}
+
+// take 取出在 now 到期的公告消息并推进轮播位置
+func (list *AnnouncementList) take(now, since time.Time) ([]pendingBroadcast, error) {
+	list.mu.Lock()
+	defer list.mu.Unlock()
+	pending := make([]pendingBroadcast, 0)
+	for i := range list.w.Data {
+		announcement := &list.w.Data[i]
+		if !announcement.due(now, since) {
+			continue
+		}
+		index := announcement.NextIndex % len(announcement.Messages)
+		pending = append(pending, pendingBroadcast{
+			id:      announcement.ID,
+			kind:    announcement.Type,
+			message: announcement.Messages[index],
+		})
+		announcement.NextIndex = (index + 1) % len(announcement.Messages)
+		sentAt := now
+		announcement.LastSentAt = &sentAt
+	}
+	if len(pending) == 0 {
+		return pending, nil
+	}
+	return pending, list.w.Write()
+}
+
+func (server *Server) AnnouncementsFilePath() string {
+	return path.Join(server.rootDir, "announcements.json")
+}
+
+func (server *Server) Announcements() *AnnouncementList {
+	return server.announcements
+}
+
+// Broadcast 向游戏内发送消息。say 以服务器身份广播；tellraw 发送给 target，默认为所有玩家
+func (server *Server) Broadcast(kind, message, target string) error {
+	message = strings.Join(strings.Fields(message), " ")
+	if message == "" {
+		return errors.New("message is required")
+	}
+	switch kind {
+	case "", BroadcastSay:
+		return server.execCmd("say", message)
+	case BroadcastTellraw:
+		if target == "" {
+			target = "@a"
+		} else if !strings.HasPrefix(target, "@") {
+			target = quoteTarget(target)
+		}
+		raw, err := json.Marshal(map[string]any{
+			"rawtext": []map[string]string{{"text": message}},
+		})
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		return server.execCmd("tellraw", target, string(raw))
+	default:
+		return errors.Errorf("unsupported broadcast type [%s]", kind)
+	}
+}
+
+// runAnnouncements 发送在 now 到期的公告，服务器未运行时不发送
+func (server *Server) runAnnouncements(now time.Time) {
+	status := server.Status()
+	if !server.Active() || status.State != StatusRunning {
+		return
+	}
+	pending, err := server.announcements.take(now, status.Since)
+	if err != nil {
+		log.WithError(err).WithField("server_id", server.id).Error("save announcements failed")
+	}
+	for _, p := range pending {
+		if err = server.Broadcast(p.kind, p.message, ""); err != nil {
+			log.WithError(err).WithField("server_id", server.id).WithField("announcement", p.id).
+				Warn("send announcement failed")
+		}
+	}
+}
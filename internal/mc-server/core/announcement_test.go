@@ -0,0 +1,105 @@
+package core
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAnnouncement_Validate(t *testing.T) {
+	cases := []Announcement{
+		{Messages: []string{" "}, IntervalMinutes: 5},
+		{Messages: []string{"hi"}},
+		{Messages: []string{"hi"}, IntervalMinutes: 5, Cron: "* * * * *"},
+		{Messages: []string{"hi"}, Cron: "bad"},
+		{Messages: []string{"hi"}, IntervalMinutes: 5, Type: "title"},
+	}
+	for _, announcement := range cases {
+		if err := announcement.Validate(); err == nil {
+			t.Errorf("expected %+v to be rejected", announcement)
+		}
+	}
+	announcement := Announcement{Messages: []string{"hi", ""}, IntervalMinutes: 5, NextIndex: 3}
+	if err := announcement.Validate(); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if announcement.Type != BroadcastSay || len(announcement.Messages) != 1 || announcement.NextIndex != 0 {
+		t.Fatalf("unexpected defaults: %+v", announcement)
+	}
+}
+
+func TestServer_Announcements(t *testing.T) {
+	server := testFakeServer(t)
+	list := server.Announcements()
+	interval, err := list.Add(Announcement{Messages: []string{"first", "second"}, IntervalMinutes: 10, Enabled: true})
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	_, err = list.Add(Announcement{Messages: []string{"hourly"}, Type: BroadcastTellraw, Cron: "0 * * * *", Enabled: true})
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	// 服务器未运行时不发送
+	now := time.Now().Truncate(time.Hour).Add(2 * time.Hour)
+	server.runAnnouncements(now)
+	for _, announcement := range list.List() {
+		if announcement.LastSentAt != nil {
+			t.Fatalf("announcement sent while stopped: %+v", announcement)
+		}
+	}
+
+	err = server.Start()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	_, err = server.process.WaitReady(5 * time.Second)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	consoleCount := func(text string) int {
+		count := 0
+		for _, line := range server.Console().Lines(0) {
+			if strings.Contains(line.Text, text) {
+				count++
+			}
+		}
+		return count
+	}
+
+	server.runAnnouncements(now)
+	server.runAnnouncements(now.Add(time.Second))
+	if consoleCount("say first") != 1 {
+		t.Fatal("expected interval announcement to be sent once")
+	}
+	if consoleCount(`tellraw @a {"rawtext":[{"text":"hourly"}]}`) != 1 {
+		t.Fatal("expected cron announcement to be sent once within the same minute")
+	}
+
+	// 未到间隔不发送，到期后轮播下一条
+	server.runAnnouncements(now.Add(5 * time.Minute))
+	if consoleCount("say second") != 0 {
+		t.Fatal("interval announcement sent too early")
+	}
+	server.runAnnouncements(now.Add(10 * time.Minute))
+	if consoleCount("say second") != 1 {
+		t.Fatal("expected rotation to the second message")
+	}
+
+	// 轮播位置持久化，重新加载后继续
+	reloaded, err := NewAnnouncementList(server.AnnouncementsFilePath())
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	for _, announcement := range reloaded.List() {
+		if announcement.ID == interval.ID && (announcement.NextIndex != 0 || announcement.LastSentAt == nil) {
+			t.Fatalf("unexpected persisted state: %+v", announcement)
+		}
+	}
+	if err = list.Delete(interval.ID); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if len(list.List()) != 1 {
+		t.Fatalf("unexpected announcements after delete: %+v", list.List())
+	}
+}
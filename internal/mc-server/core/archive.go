@@ -0,0 +1,261 @@
+package core
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/candbright/go-log/log"
+	"github.com/candbright/go-server/pkg/downloader"
+	"github.com/pkg/errors"
+)
+
+// VersionArchive 版本目录中缓存的服务器压缩包
+type VersionArchive struct {
+	Version string `json:"version"`
+	File    string `json:"file"`
+	Size    int64  `json:"size"`
+	// SHA256 下载或从镜像复制完成时记录的 SHA-256，没有记录时为空
+	SHA256  string    `json:"sha256,omitempty"`
+	ModTime time.Time `json:"mod_time"`
+	// UsedBy 当前使用该版本的服务器 id
+	UsedBy      []string `json:"used_by"`
+	Downloading bool     `json:"downloading"`
+	// Valid 压缩包的中央目录可以读取
+	Valid bool `json:"valid"`
+}
+
+// ArchiveVerification 压缩包的校验结果
+type ArchiveVerification struct {
+	Version string `json:"version"`
+	Valid   bool   `json:"valid"`
+	SHA256  string `json:"sha256,omitempty"`
+	// Expected 下载或从镜像复制完成时记录的 SHA-256
+	Expected string `json:"expected,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// checksumFile 与压缩包同目录保存 SHA-256 的文件
+func checksumFile(zipFile string) string {
+	return zipFile + ".sha256"
+}
+
+// validArchive 判断文件是否为可读取中央目录的 zip，未下载完整的压缩包缺少中央目录
+func validArchive(file string) bool {
+	r, err := zip.OpenReader(file)
+	if err != nil {
+		return false
+	}
+	_ = r.Close()
+	return true
+}
+
+func fileSHA256(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", errors.WithStack(err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// versionDownloading 判断版本压缩包是否正在下载
+func (manager *ServerManager) versionDownloading(version string) bool {
+	value, ok := manager.downloaders.Load(version)
+	if !ok {
+		return false
+	}
+	return value.(*downloader.Downloader).Running()
+}
+
+// archiveVersion 从压缩包文件名中解析版本号
+func (manager *ServerManager) archiveVersion(name string) (string, bool) {
+	version := strings.TrimSuffix(strings.TrimPrefix(name, "bedrock-server-"), ".zip")
+	if version == name || manager.ZipFileName(version) != name {
+		return "", false
+	}
+	return version, true
+}
+
+// versionUsers 返回各版本当前被哪些服务器使用
+func (manager *ServerManager) versionUsers() map[string][]string {
+	users := make(map[string][]string)
+	for id, server := range manager.GetServers() {
+		if version := server.GetVersion(); version != "" {
+			users[version] = append(users[version], id)
+		}
+	}
+	for _, ids := range users {
+		sort.Strings(ids)
+	}
+	return users
+}
+
+// ListArchives 列出版本目录中缓存的压缩包，按版本从新到旧排序。
+// 只读取已记录的 SHA-256，不在列出时计算
+func (manager *ServerManager) ListArchives() ([]VersionArchive, error) {
+	entries, err := os.ReadDir(manager.VersionsDir())
+	if os.IsNotExist(err) {
+		return []VersionArchive{}, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	users := manager.versionUsers()
+	archives := make([]VersionArchive, 0)
+	for _, entry := range entries {
+		version, ok := manager.archiveVersion(entry.Name())
+		if entry.IsDir() || !ok {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		archive := VersionArchive{
+			Version:     version,
+			File:        entry.Name(),
+			Size:        info.Size(),
+			ModTime:     info.ModTime(),
+			UsedBy:      users[version],
+			Downloading: manager.versionDownloading(version),
+		}
+		if archive.UsedBy == nil {
+			archive.UsedBy = []string{}
+		}
+		if !archive.Downloading {
+			archive.Valid = validArchive(manager.ZipFile(version))
+			archive.SHA256 = manager.storedChecksum(version)
+		}
+		archives = append(archives, archive)
+	}
+	sort.Slice(archives, func(i, j int) bool {
+		a, _ := parseVersion(archives[i].Version)
+		b, _ := parseVersion(archives[j].Version)
+		return compareVersions(a, b) > 0
+	})
+	return archives, nil
+}
+
+// storedChecksum 读取记录的 SHA-256，没有记录时返回空
+func (manager *ServerManager) storedChecksum(version string) string {
+	data, err := os.ReadFile(checksumFile(manager.ZipFile(version)))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// saveChecksum 计算并记录压缩包的 SHA-256，只在下载或从镜像复制完成时调用
+func (manager *ServerManager) saveChecksum(version string) error {
+	file := manager.ZipFile(version)
+	sum, err := fileSHA256(file)
+	if err != nil {
+		return err
+	}
+	return errors.WithStack(os.WriteFile(checksumFile(file), []byte(sum), 0666))
+}
+
+// VerifyArchive 重新校验压缩包：中央目录可以读取，且 SHA-256 与下载完成时记录的一致。
+// 没有记录过 SHA-256 时只校验中央目录
+func (manager *ServerManager) VerifyArchive(version string) (ArchiveVerification, error) {
+	file := manager.ZipFile(version)
+	if !Exists(file) {
+		return ArchiveVerification{}, errors.Errorf("archive of version [%s] not found", version)
+	}
+	if manager.versionDownloading(version) {
+		return ArchiveVerification{}, errors.Errorf("version [%s] is downloading", version)
+	}
+	result := ArchiveVerification{Version: version}
+	r, err := zip.OpenReader(file)
+	if err != nil {
+		result.Error = "read zip central directory: " + err.Error()
+		return result, nil
+	}
+	_ = r.Close()
+	result.SHA256, err = fileSHA256(file)
+	if err != nil {
+		return ArchiveVerification{}, err
+	}
+	result.Expected = manager.storedChecksum(version)
+	if result.Expected != "" && result.Expected != result.SHA256 {
+		result.Error = "sha256 mismatch"
+		return result, nil
+	}
+	result.Valid = true
+	return result, nil
+}
+
+// DeleteArchive 删除没有服务器使用、也不在下载中的版本压缩包
+func (manager *ServerManager) DeleteArchive(version string) error {
+	file := manager.ZipFile(version)
+	if _, ok := manager.archiveVersion(path.Base(file)); !ok || !Exists(file) {
+		return errors.Errorf("archive of version [%s] not found", version)
+	}
+	if manager.versionDownloading(version) {
+		return errors.Errorf("version [%s] is downloading", version)
+	}
+	if users := manager.versionUsers()[version]; len(users) > 0 {
+		return errors.Errorf("version [%s] is used by servers %s", version, strings.Join(users, ", "))
+	}
+	manager.downloaders.Delete(version)
+	err := os.Remove(file)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = os.Remove(checksumFile(file))
+	if err != nil && !os.IsNotExist(err) {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// PruneArchives 删除所有没有服务器使用的压缩包，返回被删除的版本
+func (manager *ServerManager) PruneArchives() ([]string, error) {
+	entries, err := os.ReadDir(manager.VersionsDir())
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	users := manager.versionUsers()
+	deleted := make([]string, 0)
+	for _, entry := range entries {
+		version, ok := manager.archiveVersion(entry.Name())
+		if entry.IsDir() || !ok || len(users[version]) > 0 || manager.versionDownloading(version) {
+			continue
+		}
+		if err = manager.DeleteArchive(version); err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, version)
+	}
+	return deleted, nil
+}
+
+// PreDownloadVersion 在后台下载版本压缩包但不关联到任何服务器，已下载时不做任何事
+func (manager *ServerManager) PreDownloadVersion(version string) error {
+	if _, ok := parseVersion(version); !ok {
+		return errors.Errorf("invalid version [%s]", version)
+	}
+	if manager.ZipExist(version) || manager.versionDownloading(version) {
+		return nil
+	}
+	go func() {
+		if err := manager.fetchVersion(version); err != nil {
+			log.WithError(err).WithField("version", version).Error("pre-download version failed")
+		}
+	}()
+	return nil
+}
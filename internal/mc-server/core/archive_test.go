@@ -0,0 +1,105 @@
+package core
+
+import (
+	"os"
+	"testing"
+)
+
+func TestServerManager_Archives(t *testing.T) {
+	server := testFakeServer(t)
+	manager := testManager(t, server)
+	files := map[string]string{"bedrock_server": fakeServerScript}
+	writeTestZip(t, manager.ZipFile("fake"), files)
+	writeTestZip(t, manager.ZipFile("1.21.62.01"), files)
+	writeTestZip(t, manager.ZipFile("1.21.50.10"), files)
+	// 未下载完整的压缩包缺少中央目录
+	data, err := os.ReadFile(manager.ZipFile("1.21.50.10"))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err = os.WriteFile(manager.ZipFile("1.21.50.10"), data[:len(data)/2], 0666); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if manager.ZipExist("1.21.50.10") || !manager.ZipExist("1.21.62.01") {
+		t.Fatal("ZipExist should reject incomplete archives")
+	}
+	// 只有下载或复制完成的压缩包记录了校验和
+	if err = manager.saveChecksum("1.21.62.01"); err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	archives, err := manager.ListArchives()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if len(archives) != 3 || archives[0].Version != "1.21.62.01" || archives[1].Version != "1.21.50.10" {
+		t.Fatalf("unexpected archives: %+v", archives)
+	}
+	for _, archive := range archives {
+		switch archive.Version {
+		case "fake":
+			if len(archive.UsedBy) != 1 || archive.UsedBy[0] != server.id {
+				t.Errorf("unexpected users: %+v", archive)
+			}
+			// 列出时不计算校验和
+			if !archive.Valid || archive.SHA256 != "" {
+				t.Errorf("archive without a recorded checksum should list none: %+v", archive)
+			}
+		case "1.21.50.10":
+			if archive.Valid || archive.SHA256 != "" {
+				t.Errorf("incomplete archive should be invalid: %+v", archive)
+			}
+		default:
+			if !archive.Valid || len(archive.SHA256) != 64 || archive.Size == 0 {
+				t.Errorf("unexpected archive: %+v", archive)
+			}
+		}
+	}
+
+	result, err := manager.VerifyArchive("1.21.62.01")
+	if err != nil || !result.Valid || result.Expected != result.SHA256 {
+		t.Fatalf("%+v %+v", result, err)
+	}
+	result, err = manager.VerifyArchive("fake")
+	if err != nil || !result.Valid || result.Expected != "" || Exists(checksumFile(manager.ZipFile("fake"))) {
+		t.Fatalf("verify should not record a checksum: %+v %+v", result, err)
+	}
+	result, err = manager.VerifyArchive("1.21.50.10")
+	if err != nil || result.Valid || result.Error == "" {
+		t.Fatalf("%+v %+v", result, err)
+	}
+	// 末尾追加数据后中央目录仍可读取，但校验和不一致
+	f, err := os.OpenFile(manager.ZipFile("1.21.62.01"), os.O_APPEND|os.O_WRONLY, 0666)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	_, _ = f.Write([]byte("garbage"))
+	_ = f.Close()
+	result, err = manager.VerifyArchive("1.21.62.01")
+	if err != nil || result.Valid || result.Error != "sha256 mismatch" {
+		t.Fatalf("%+v %+v", result, err)
+	}
+
+	if err = manager.DeleteArchive("fake"); err == nil {
+		t.Fatal("archive used by a server should not be deleted")
+	}
+	if err = manager.DeleteArchive("../fake"); err == nil {
+		t.Fatal("invalid version should be rejected")
+	}
+	if err = manager.DeleteArchive("1.21.62.01"); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if Exists(manager.ZipFile("1.21.62.01")) || Exists(checksumFile(manager.ZipFile("1.21.62.01"))) {
+		t.Fatal("archive and checksum should be removed")
+	}
+	deleted, err := manager.PruneArchives()
+	if err != nil || len(deleted) != 1 || deleted[0] != "1.21.50.10" {
+		t.Fatalf("%v %+v", deleted, err)
+	}
+	if !Exists(manager.ZipFile("fake")) {
+		t.Fatal("used archive should be kept")
+	}
+	if err = manager.PreDownloadVersion("latest"); err == nil {
+		t.Fatal("invalid version should be rejected")
+	}
+}
@@ -0,0 +1,274 @@
+package core
+
+import (
+	"fmt"
+	"os/exec"
+	"path"
+	"testing"
+	"time"
+)
+
+// testBackends 返回当前系统上可以用假服务器测试的会话后端，缺少依赖程序的后端跳过
+func testBackends(t *testing.T) []string {
+	backends := make([]string, 0)
+	for _, backend := range SupportedBackends() {
+		switch backend {
+		case BackendTmux, BackendScreen:
+			if _, err := exec.LookPath(backend); err != nil {
+				t.Logf("%s not installed, skipping its conformance tests", backend)
+				continue
+			}
+		case BackendWindow:
+			// 窗口后端按进程名查找真实的 bedrock_server.exe，无法使用假服务器
+			continue
+		}
+		backends = append(backends, backend)
+	}
+	return backends
+}
+
+// forEachBackend 对每个可测试的后端运行同一组测试，所有后端都必须通过
+func forEachBackend(t *testing.T, test func(t *testing.T, backend string)) {
+	for _, backend := range testBackends(t) {
+		backend := backend
+		t.Run(backend, func(t *testing.T) {
+			test(t, backend)
+		})
+	}
+}
+
+func TestBackend_Validate(t *testing.T) {
+	for _, backend := range SupportedBackends() {
+		if err := ValidateBackend(backend); err != nil {
+			t.Fatalf("%+v", err)
+		}
+	}
+	if err := ValidateBackend("bogus"); err == nil {
+		t.Fatal("unknown backend should be rejected")
+	}
+	if _, err := NewSessionBackend("bogus", BackendConfig{}); err == nil {
+		t.Fatal("creating an unknown backend should fail")
+	}
+	process := NewProcess(ProcessConfig{RootDir: t.TempDir(), Backend: "bogus"})
+	if process.Backend() != DefaultBackend {
+		t.Fatalf("expected fallback to %s, got %s", DefaultBackend, process.Backend())
+	}
+}
+
+func TestBackend_StartAndCommand(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend string) {
+		process := testProcess(t, backend)
+		if process.Backend() != backend {
+			t.Fatalf("expected backend %s, got %s", backend, process.Backend())
+		}
+		if process.Active() {
+			t.Fatal("process should not be active before start")
+		}
+		err := process.Start()
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		if !process.Active() {
+			t.Fatal("process should be active after start")
+		}
+		if err = process.Start(); err == nil {
+			t.Fatal("starting a running process should fail")
+		}
+		status, err := process.WaitReady(5 * time.Second)
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		if status.State != StatusRunning || status.IPv4Port != 19132 {
+			t.Fatalf("unexpected status after start: %+v", status)
+		}
+		lines, err := process.ExecCmdOutput(2*time.Second, "list")
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		if len(lines) != 1 || lines[0] != "[INFO] list" {
+			t.Fatalf("unexpected command output: %q", lines)
+		}
+		_, err = process.ExecCmdOutput(2*time.Second, "bogus")
+		if err == nil {
+			t.Fatal("expected unknown command to fail")
+		}
+		// 与按键名相同的命令按文本发送
+		lines, err = process.ExecCmdOutput(2*time.Second, "Enter")
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		if len(lines) != 1 || lines[0] != "[INFO] Enter" {
+			t.Fatalf("unexpected output for a key name: %q", lines)
+		}
+	})
+}
+
+func TestBackend_State(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend string) {
+		process := testProcess(t, backend)
+		err := process.Start()
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		waitStarted(t, process)
+		state := process.session.State()
+		if state.Backend != backend {
+			t.Fatalf("expected state backend %s, got %s", backend, state.Backend)
+		}
+		if state.Pid <= 0 {
+			t.Fatal("state should contain the server pid")
+		}
+	})
+}
+
+func TestBackend_Shutdown(t *testing.T) {
+	defer func(timeout time.Duration) {
+		terminateTimeout = timeout
+	}(terminateTimeout)
+	terminateTimeout = 2 * time.Second
+	tests := []struct {
+		name   string
+		script string
+		method string
+	}{
+		{name: "stop command", script: fakeServerScript, method: StopMethodCommand},
+		{name: "sigterm", script: fmt.Sprintf(stubbornServerScript, ""), method: StopMethodSigterm},
+	}
+	forEachBackend(t, func(t *testing.T, backend string) {
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				process := testProcessWithScript(t, backend, tt.script)
+				process.stopTimeout = time.Second
+				err := process.Start()
+				if err != nil {
+					t.Fatalf("%+v", err)
+				}
+				waitStarted(t, process)
+				result, err := process.Shutdown()
+				if err != nil {
+					t.Fatalf("%+v", err)
+				}
+				if result.Method != tt.method {
+					t.Fatalf("expected method %s, got %s", tt.method, result.Method)
+				}
+				if process.Active() {
+					t.Fatal("process should not be active after shutdown")
+				}
+			})
+		}
+	})
+}
+
+func TestBackend_Kill(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend string) {
+		process := testProcessWithScript(t, backend, fmt.Sprintf(stubbornServerScript, "trap '' TERM"))
+		err := process.Start()
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		waitStarted(t, process)
+		err = process.session.Kill()
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		waitFor(t, 5*time.Second, func() bool {
+			return !process.Active()
+		})
+	})
+}
+
+func TestBackend_Attach(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend string) {
+		dir := t.TempDir()
+		writeFakeServer(t, dir, fakeServerScript)
+		cfg := ProcessConfig{
+			RootDir:   dir,
+			Backend:   backend,
+			StateFile: path.Join(dir, "process.json"),
+		}
+		first := NewProcess(cfg)
+		err := first.Start()
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		t.Cleanup(func() {
+			if first.Active() {
+				_ = first.session.Kill()
+			}
+		})
+		waitStarted(t, first)
+
+		// 全局配置改为其他后端后，仍应沿用进程启动时的后端接管
+		cfg.Backend = "bogus"
+		second := NewProcess(cfg)
+		if second.Backend() != backend {
+			t.Fatalf("expected backend %s from state file, got %s", backend, second.Backend())
+		}
+		if !second.Attach() || !second.Active() {
+			t.Fatal("expected to attach to the running process")
+		}
+		lines, err := second.ExecCmdOutput(2*time.Second, "list")
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		if len(lines) != 1 || lines[0] != "[INFO] list" {
+			t.Fatalf("unexpected output after attach: %q", lines)
+		}
+		_, err = second.Shutdown()
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		if second.Active() {
+			t.Fatal("process should not be active after shutdown")
+		}
+	})
+}
+
+// sessionLogFile 返回会话后端写入输出的日志文件
+func sessionLogFile(t *testing.T, process *Process) string {
+	switch session := process.session.(type) {
+	case *Supervisor:
+		return session.consoleLog()
+	case interface{ LogFile() string }:
+		return session.LogFile()
+	}
+	t.Fatalf("backend %s has no log file", process.Backend())
+	return ""
+}
+
+func TestBackend_LogRotation(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend string) {
+		// 在进程结束后才恢复，避免读取日志的协程使用到测试中的大小
+		t.Cleanup(func(size int64) func() {
+			return func() { maxConsoleLogSize = size }
+		}(maxConsoleLogSize))
+		maxConsoleLogSize = 512
+		process := testProcess(t, backend)
+		err := process.Start()
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		waitStarted(t, process)
+		logFile := sessionLogFile(t, process)
+		for i := 0; i < 40; i++ {
+			if err = process.ExecCmd(fmt.Sprintf("say padding line %02d to grow the log", i)); err != nil {
+				t.Fatalf("%+v", err)
+			}
+		}
+		waitFor(t, 10*time.Second, func() bool {
+			lines := process.Console().Lines(1)
+			return Exists(logFile+".1") && len(lines) == 1 && lines[0].Text == "[INFO] say padding line 39 to grow the log"
+		})
+		// 轮转后仍能读取新的输出
+		lines, err := process.ExecCmdOutput(2*time.Second, "list")
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		if len(lines) != 1 || lines[0] != "[INFO] list" {
+			t.Fatalf("unexpected output after rotation: %q", lines)
+		}
+		if size := fileSize(logFile); size > 2*maxConsoleLogSize {
+			t.Fatalf("log should be rotated, got %d bytes", size)
+		}
+	})
+}
@@ -0,0 +1,207 @@
+package core
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/candbright/go-log/log"
+	"github.com/candbright/go-server/pkg/dw"
+	"github.com/pkg/errors"
+)
+
+// BanEntry 面板维护的一条封禁记录，按 xuid 或玩家名匹配
+type BanEntry struct {
+	Name     string    `json:"name,omitempty"`
+	Xuid     string    `json:"xuid,omitempty"`
+	Reason   string    `json:"reason,omitempty"`
+	BannedAt time.Time `json:"banned_at"`
+	// ExpiresAt 临时封禁的到期时间，为空表示永久封禁
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+func (entry BanEntry) Expired(now time.Time) bool {
+	return entry.ExpiresAt != nil && !now.Before(*entry.ExpiresAt)
+}
+
+// Matches 判断玩家是否命中该封禁，xuid 优先，玩家名不区分大小写
+func (entry BanEntry) Matches(name, xuid string) bool {
+	if entry.Xuid != "" && xuid != "" {
+		return entry.Xuid == xuid
+	}
+	return entry.Name != "" && strings.EqualFold(entry.Name, name)
+}
+
+// KickMessage 踢出被封禁玩家时显示的提示
+func (entry BanEntry) KickMessage() string {
+	message := "You are banned from this server"
+	if entry.Reason != "" {
+		message += ": " + entry.Reason
+	}
+	if entry.ExpiresAt != nil {
+		message += fmt.Sprintf(" (until %s)", entry.ExpiresAt.Local().Format("2006-01-02 15:04"))
+	}
+	return message
+}
+
+// BanList 持久化的服务器封禁列表
+type BanList struct {
+	mu sync.Mutex
+	w  *dw.DataWriter[[]BanEntry]
+}
+
+func NewBanList(file string) (*BanList, error) {
+	w, err := dw.JsonOrDefault[[]BanEntry](file, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &BanList{w: w}, nil
+}
+
+// List 返回仍然有效的封禁记录
+func (list *BanList) List() []BanEntry {
+	list.mu.Lock()
+	defer list.mu.Unlock()
+	now := time.Now()
+	entries := make([]BanEntry, 0, len(list.w.Data))
+	for _, entry := range list.w.Data {
+		if !entry.Expired(now) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// Find 查找命中玩家的有效封禁
+func (list *BanList) Find(name, xuid string) (BanEntry, bool) {
+	for _, entry := range list.List() {
+		if entry.Matches(name, xuid) {
+			return entry, true
+		}
+	}
+	return BanEntry{}, false
+}
+
+// Add 添加封禁，同一玩家已有的封禁会被替换，写入时清理已过期的记录
+func (list *BanList) Add(entry BanEntry) error {
+	if entry.Name == "" && entry.Xuid == "" {
+		return errors.New("name or xuid is required")
+	}
+	if entry.BannedAt.IsZero() {
+		entry.BannedAt = time.Now()
+	}
+	if entry.Expired(entry.BannedAt) {
+		return errors.New("expiry time must be in the future")
+	}
+	list.mu.Lock()
+	defer list.mu.Unlock()
+	entries := list.without(entry.Name, entry.Xuid)
+	list.w.Data = append(entries, entry)
+	return list.w.Write()
+}
+
+// Remove 解除玩家的封禁，没有对应记录时返回 false
+func (list *BanList) Remove(name, xuid string) (bool, error) {
+	list.mu.Lock()
+	defer list.mu.Unlock()
+	entries := list.without(name, xuid)
+	removed := len(entries) != len(list.w.Data)
+	list.w.Data = entries
+	if !removed {
+		return false, nil
+	}
+	return true, list.w.Write()
+}
+
+// without 返回去掉 xuid 或玩家名相同的记录以及已过期记录后的列表
+func (list *BanList) without(name, xuid string) []BanEntry {
+	now := time.Now()
+	entries := make([]BanEntry, 0, len(list.w.Data))
+	for _, entry := range list.w.Data {
+		if entry.Expired(now) || (xuid != "" && entry.Xuid == xuid) ||
+			(name != "" && strings.EqualFold(entry.Name, name)) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// quoteTarget 玩家名包含空格时需要加引号才能作为命令目标
+func quoteTarget(name string) string {
+	if strings.ContainsAny(name, " \t") {
+		return fmt.Sprintf("%q", name)
+	}
+	return name
+}
+
+// Kick 将在线玩家踢出服务器，原因总是加引号，避免被拆分成多个参数
+func (server *Server) Kick(name, reason string) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New("player name is required")
+	}
+	arg := []string{"kick", quoteTarget(name)}
+	if reason != "" {
+		arg = append(arg, fmt.Sprintf("%q", reason))
+	}
+	return server.execCmd(arg...)
+}
+
+func (server *Server) BanFilePath() string {
+	return path.Join(server.rootDir, "bans.json")
+}
+
+func (server *Server) Bans() []BanEntry {
+	return server.bans.List()
+}
+
+// Ban 添加封禁，玩家在线时立即踢出。只提供玩家名或 xuid 时从在线玩家中补全另一项
+func (server *Server) Ban(entry BanEntry) (BanEntry, error) {
+	for _, player := range server.OnlinePlayers() {
+		if entry.Matches(player.Name, player.Xuid) {
+			if entry.Name == "" {
+				entry.Name = player.Name
+			}
+			if entry.Xuid == "" {
+				entry.Xuid = player.Xuid
+			}
+		}
+	}
+	entry.BannedAt = time.Now()
+	if err := server.bans.Add(entry); err != nil {
+		return entry, err
+	}
+	for _, player := range server.OnlinePlayers() {
+		if entry.Matches(player.Name, player.Xuid) {
+			if err := server.Kick(player.Name, entry.KickMessage()); err != nil {
+				log.WithError(err).WithField("player", player.Name).Warn("kick banned player failed")
+			}
+		}
+	}
+	return entry, nil
+}
+
+func (server *Server) Unban(name, xuid string) (bool, error) {
+	return server.bans.Remove(name, xuid)
+}
+
+// enforceBan 玩家进入服务器时检查封禁列表，命中则踢出
+func (server *Server) enforceBan(event PlayerEvent) {
+	if event.Type != PlayerJoin {
+		return
+	}
+	entry, ok := server.bans.Find(event.Name, event.Xuid)
+	if !ok {
+		return
+	}
+	logger := log.WithField("server_id", server.id).WithField("player", event.Name)
+	logger.Info("banned player connected, kicking")
+	// 在控制台回调中同步执行命令会阻塞输出读取，须异步执行
+	go func() {
+		if err := server.Kick(event.Name, entry.KickMessage()); err != nil {
+			logger.WithError(err).Warn("kick banned player failed")
+		}
+	}()
+}
@@ -0,0 +1,152 @@
+package core
+
+import (
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBanList(t *testing.T) {
+	file := path.Join(t.TempDir(), "bans.json")
+	list, err := NewBanList(file)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err = list.Add(BanEntry{}); err == nil {
+		t.Fatal("ban without name or xuid should fail")
+	}
+	past := time.Now().Add(-time.Minute)
+	if err = list.Add(BanEntry{Name: "Steve", ExpiresAt: &past}); err == nil {
+		t.Fatal("ban that already expired should fail")
+	}
+	err = list.Add(BanEntry{Name: "Steve", Xuid: "100", Reason: "griefing"})
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	soon := time.Now().Add(200 * time.Millisecond)
+	err = list.Add(BanEntry{Name: "Alex", ExpiresAt: &soon})
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	// 重新加载后封禁仍然有效，xuid 优先匹配，名字不区分大小写
+	list, err = NewBanList(file)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if entry, ok := list.Find("Renamed", "100"); !ok || entry.Reason != "griefing" {
+		t.Fatalf("expected ban matched by xuid, got %+v", entry)
+	}
+	if _, ok := list.Find("steve", ""); !ok {
+		t.Fatal("expected ban matched by name")
+	}
+	if _, ok := list.Find("Steve", "999"); ok {
+		t.Fatal("different xuid should not match")
+	}
+	if _, ok := list.Find("alex", "200"); !ok {
+		t.Fatal("expected temporary ban matched by name")
+	}
+	waitFor(t, time.Second, func() bool {
+		_, ok := list.Find("alex", "200")
+		return !ok
+	})
+	if entries := list.List(); len(entries) != 1 {
+		t.Fatalf("expired ban should not be listed: %+v", entries)
+	}
+
+	removed, err := list.Remove("", "100")
+	if err != nil || !removed {
+		t.Fatalf("expected ban removed, err: %v", err)
+	}
+	if removed, _ = list.Remove("Steve", ""); removed {
+		t.Fatal("removing a missing ban should return false")
+	}
+}
+
+func TestServer_BanEnforced(t *testing.T) {
+	server := testFakeServer(t)
+	err := server.Start()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	_, err = server.process.WaitReady(5 * time.Second)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	kicked := func(name string) bool {
+		for _, line := range server.Console().Lines(0) {
+			if strings.HasPrefix(line.Text, "[INFO] kick "+name+` "You are banned`) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// 在线玩家被封禁后立即踢出，并补全 xuid
+	_ = server.process.ExecCmd("join", "Steve", "100")
+	waitFor(t, 5*time.Second, func() bool {
+		return len(server.OnlinePlayers()) == 1
+	})
+	entry, err := server.Ban(BanEntry{Name: "Steve", Reason: "griefing"})
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if entry.Xuid != "100" {
+		t.Fatalf("expected xuid filled from online players, got %+v", entry)
+	}
+	waitFor(t, 5*time.Second, func() bool {
+		return kicked("Steve")
+	})
+
+	// 被封禁的玩家连接时被踢出
+	_, err = server.Ban(BanEntry{Xuid: "200"})
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	_ = server.process.ExecCmd("join", "Alex", "200")
+	waitFor(t, 5*time.Second, func() bool {
+		return kicked("Alex")
+	})
+	removed, err := server.Unban("", "200")
+	if err != nil || !removed {
+		t.Fatalf("expected unban, err: %v", err)
+	}
+}
+
+func TestServer_KickLineBreaks(t *testing.T) {
+	server := testFakeServer(t)
+	err := server.Start()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	_, err = server.process.WaitReady(5 * time.Second)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err = server.Kick("Steve\nstop", ""); err == nil {
+		t.Fatal("player name with a line break should be rejected")
+	}
+	// 原因总是加引号，其中的换行被转义，不会成为单独的命令
+	if err = server.Kick("Steve", "bye\r\nstop"); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	time.Sleep(500 * time.Millisecond)
+	if !server.Active() {
+		t.Fatal("server should still be running")
+	}
+	for _, line := range server.Console().Lines(0) {
+		if strings.Contains(line.Text, "Stopping server") {
+			t.Fatalf("injected command was executed: %q", line.Text)
+		}
+	}
+}
+
+func TestQuoteTarget(t *testing.T) {
+	if quoteTarget("Steve") != "Steve" {
+		t.Fatal("simple name should not be quoted")
+	}
+	if quoteTarget("Big Alex") != `"Big Alex"` {
+		t.Fatal("name with spaces should be quoted")
+	}
+}
@@ -0,0 +1,349 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/candbright/go-log/log"
+	"github.com/pkg/errors"
+)
+
+const (
+	ChannelStable  = "stable"
+	ChannelPreview = "preview"
+)
+
+// DefaultVersionCatalogURL Mojang 官方的下载链接接口，返回包含各平台服务器压缩包地址的 JSON
+const DefaultVersionCatalogURL = "https://net-secondary.web.minecraft-services.net/api/v1.0/download/links"
+
+const (
+	// versionCatalogTimeout 请求远程版本目录的超时时间
+	versionCatalogTimeout = 30 * time.Second
+	// versionLookupTimeout 下载时查询版本目录的最长等待时间，超时后使用内置的下载地址
+	versionLookupTimeout = 5 * time.Second
+)
+
+// serverZipPattern 匹配服务器压缩包的下载地址或文件名，第一个分组为版本号
+var serverZipPattern = regexp.MustCompile(`(?:https?://[^\s"'<>]*/)?bedrock-server-(\d+(?:\.\d+)+)\.zip`)
+
+// VersionInfo 版本目录中的一个可用版本
+type VersionInfo struct {
+	Version string `json:"version"`
+	// Channel 发布渠道，stable 或 preview
+	Channel string `json:"channel"`
+	// URL 当前平台服务器压缩包的下载地址
+	URL string `json:"url,omitempty"`
+	// File 本地镜像中的压缩包路径，存在时直接复制而不下载
+	File string `json:"-"`
+	// Source 提供该版本的来源名称
+	Source string `json:"source"`
+}
+
+// VersionSource 版本目录的来源
+type VersionSource interface {
+	Name() string
+	// Versions 返回来源中当前平台可用的版本，顺序不限
+	Versions(ctx context.Context) ([]VersionInfo, error)
+}
+
+// VersionManifest 本地清单文件和远程 JSON 的通用格式
+type VersionManifest struct {
+	Versions []VersionInfo `json:"versions"`
+}
+
+// HTTPVersionSource 从远程地址获取版本目录。响应为 VersionManifest 格式的 JSON 时直接使用，
+// 否则（Mojang 的下载链接接口或下载页面 HTML）从中提取服务器压缩包的下载地址
+type HTTPVersionSource struct {
+	URL    string
+	Client *http.Client
+}
+
+func NewHTTPVersionSource(url string) *HTTPVersionSource {
+	return &HTTPVersionSource{URL: url, Client: &http.Client{Timeout: versionCatalogTimeout}}
+}
+
+func (source *HTTPVersionSource) Name() string {
+	return "http"
+}
+
+func (source *HTTPVersionSource) Versions(ctx context.Context) ([]VersionInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source.URL, nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// 下载页面会拒绝没有浏览器标识的请求
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; go-server)")
+	resp, err := source.Client.Do(req)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("fetch %s: unexpected status %s", source.URL, resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var manifest VersionManifest
+	if json.Unmarshal(body, &manifest) == nil && len(manifest.Versions) > 0 {
+		return normalizeManifest(manifest.Versions, source.Name())
+	}
+	return extractServerLinks(string(body), source.Name()), nil
+}
+
+// ManifestVersionSource 从本地 VersionManifest 格式的 JSON 文件读取版本目录，适用于离线部署
+type ManifestVersionSource struct {
+	File string
+}
+
+func (source *ManifestVersionSource) Name() string {
+	return "manifest"
+}
+
+func (source *ManifestVersionSource) Versions(context.Context) ([]VersionInfo, error) {
+	data, err := os.ReadFile(source.File)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var manifest VersionManifest
+	if err = json.Unmarshal(data, &manifest); err != nil {
+		return nil, errors.Wrapf(err, "parse manifest %s", source.File)
+	}
+	return normalizeManifest(manifest.Versions, source.Name())
+}
+
+// MirrorVersionSource 扫描本地镜像目录中的 bedrock-server-<version>.zip，preview 子目录中的为预览版
+type MirrorVersionSource struct {
+	Dir string
+}
+
+func (source *MirrorVersionSource) Name() string {
+	return "mirror"
+}
+
+func (source *MirrorVersionSource) Versions(context.Context) ([]VersionInfo, error) {
+	versions := make([]VersionInfo, 0)
+	for channel, dir := range map[string]string{
+		ChannelStable:  source.Dir,
+		ChannelPreview: path.Join(source.Dir, ChannelPreview),
+	} {
+		entries, err := os.ReadDir(dir)
+		if os.IsNotExist(err) && channel == ChannelPreview {
+			continue
+		}
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		for _, entry := range entries {
+			match := serverZipPattern.FindStringSubmatch(entry.Name())
+			if entry.IsDir() || match == nil || match[0] != entry.Name() {
+				continue
+			}
+			versions = append(versions, VersionInfo{
+				Version: match[1],
+				Channel: channel,
+				File:    path.Join(dir, entry.Name()),
+				Source:  source.Name(),
+			})
+		}
+	}
+	return versions, nil
+}
+
+// normalizeManifest 校验清单中的版本并补全默认渠道
+func normalizeManifest(versions []VersionInfo, source string) ([]VersionInfo, error) {
+	result := make([]VersionInfo, 0, len(versions))
+	for _, v := range versions {
+		if _, ok := parseVersion(v.Version); !ok {
+			return nil, errors.Errorf("invalid version [%s]", v.Version)
+		}
+		if v.Channel == "" {
+			v.Channel = ChannelStable
+		}
+		if v.Channel != ChannelStable && v.Channel != ChannelPreview {
+			return nil, errors.Errorf("version [%s] has unsupported channel [%s]", v.Version, v.Channel)
+		}
+		v.File = ""
+		v.Source = source
+		result = append(result, v)
+	}
+	return result, nil
+}
+
+// extractServerLinks 从任意文本中提取当前平台的服务器压缩包下载地址，地址中含 preview 的为预览版
+func extractServerLinks(content, source string) []VersionInfo {
+	platform := "bin-linux"
+	if runtime.GOOS == "windows" {
+		platform = "bin-win"
+	}
+	versions := make([]VersionInfo, 0)
+	seen := make(map[string]bool)
+	for _, match := range serverZipPattern.FindAllStringSubmatch(content, -1) {
+		url := match[0]
+		if !strings.HasPrefix(url, "http") || !strings.Contains(url, platform) || seen[url] {
+			continue
+		}
+		seen[url] = true
+		channel := ChannelStable
+		if strings.Contains(url, "preview") {
+			channel = ChannelPreview
+		}
+		versions = append(versions, VersionInfo{Version: match[1], Channel: channel, URL: url, Source: source})
+	}
+	return versions
+}
+
+// VersionCatalog 汇总多个来源的可用版本并缓存 ttl 时间。
+// 同一版本以先配置的来源为准；来源全部失败时沿用过期的缓存。
+// 同一时间只有一次获取在进行，获取期间不持有锁，其他调用方等待同一次获取的结果
+type VersionCatalog struct {
+	sources   []VersionSource
+	ttl       time.Duration
+	mu        sync.Mutex
+	versions  []VersionInfo
+	fetchedAt time.Time
+	// loading 正在进行的获取完成时关闭，没有获取在进行时为 nil
+	loading chan struct{}
+	// loadErr 最近一次获取的错误
+	loadErr error
+}
+
+func NewVersionCatalog(ttl time.Duration, sources ...VersionSource) *VersionCatalog {
+	if ttl <= 0 {
+		ttl = time.Hour
+	}
+	return &VersionCatalog{sources: sources, ttl: ttl}
+}
+
+// Versions 返回 channel 渠道的可用版本，从新到旧排序；channel 为空时返回全部，refresh 为 true 时忽略缓存
+func (catalog *VersionCatalog) Versions(ctx context.Context, channel string, refresh bool) ([]VersionInfo, error) {
+	if channel != "" && channel != ChannelStable && channel != ChannelPreview {
+		return nil, errors.Errorf("unsupported channel [%s]", channel)
+	}
+	all, err := catalog.load(ctx, refresh)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]VersionInfo, 0, len(all))
+	for _, v := range all {
+		if channel == "" || v.Channel == channel {
+			versions = append(versions, v)
+		}
+	}
+	return versions, nil
+}
+
+// Latest 返回 channel 渠道的最新版本
+func (catalog *VersionCatalog) Latest(ctx context.Context, channel string) (VersionInfo, error) {
+	versions, err := catalog.Versions(ctx, channel, false)
+	if err != nil {
+		return VersionInfo{}, err
+	}
+	if len(versions) == 0 {
+		return VersionInfo{}, errors.Errorf("no %s version available", channel)
+	}
+	return versions[0], nil
+}
+
+// Lookup 在目录中查找指定版本
+func (catalog *VersionCatalog) Lookup(ctx context.Context, version string) (VersionInfo, bool) {
+	versions, err := catalog.Versions(ctx, "", false)
+	if err != nil {
+		return VersionInfo{}, false
+	}
+	for _, v := range versions {
+		if v.Version == version {
+			return v, true
+		}
+	}
+	return VersionInfo{}, false
+}
+
+// load 返回缓存的版本，缓存过期或 refresh 为 true 时等待一次获取。
+// 获取使用独立的超时，ctx 只决定调用方等待多久，等待超时时沿用过期的缓存
+func (catalog *VersionCatalog) load(ctx context.Context, refresh bool) ([]VersionInfo, error) {
+	catalog.mu.Lock()
+	if !refresh && catalog.versions != nil && time.Since(catalog.fetchedAt) < catalog.ttl {
+		defer catalog.mu.Unlock()
+		return catalog.versions, nil
+	}
+	if len(catalog.sources) == 0 {
+		catalog.mu.Unlock()
+		return nil, errors.New("no version source configured")
+	}
+	if catalog.loading == nil {
+		catalog.loading = make(chan struct{})
+		go catalog.fetch(catalog.loading)
+	}
+	loading := catalog.loading
+	catalog.mu.Unlock()
+
+	select {
+	case <-loading:
+	case <-ctx.Done():
+	}
+	catalog.mu.Lock()
+	defer catalog.mu.Unlock()
+	if catalog.versions != nil {
+		return catalog.versions, nil
+	}
+	select {
+	case <-loading:
+		return nil, catalog.loadErr
+	default:
+		return nil, errors.WithStack(ctx.Err())
+	}
+}
+
+// fetch 依次请求所有来源并更新缓存，完成后关闭 done
+func (catalog *VersionCatalog) fetch(done chan struct{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), versionCatalogTimeout)
+	defer cancel()
+	merged := make([]VersionInfo, 0)
+	seen := make(map[string]bool)
+	var lastErr error
+	succeeded := false
+	for _, source := range catalog.sources {
+		versions, err := source.Versions(ctx)
+		if err != nil {
+			log.WithError(err).WithField("source", source.Name()).Warn("load version catalog failed")
+			lastErr = err
+			continue
+		}
+		succeeded = true
+		for _, v := range versions {
+			if seen[v.Version] {
+				continue
+			}
+			seen[v.Version] = true
+			merged = append(merged, v)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		a, _ := parseVersion(merged[i].Version)
+		b, _ := parseVersion(merged[j].Version)
+		return compareVersions(a, b) > 0
+	})
+
+	catalog.mu.Lock()
+	defer catalog.mu.Unlock()
+	catalog.loadErr = lastErr
+	if succeeded {
+		catalog.versions = merged
+		catalog.fetchedAt = time.Now()
+		catalog.loadErr = nil
+	}
+	catalog.loading = nil
+	close(done)
+}
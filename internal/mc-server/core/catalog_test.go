@@ -0,0 +1,206 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// mojangLinks 模拟 Mojang 下载链接接口的响应
+const mojangLinks = `{"result":{"links":[
+{"downloadType":"serverBedrockWindows","downloadUrl":"https://www.minecraft.net/bedrockdedicatedserver/bin-win/bedrock-server-1.21.62.01.zip"},
+{"downloadType":"serverBedrockLinux","downloadUrl":"https://www.minecraft.net/bedrockdedicatedserver/bin-linux/bedrock-server-1.21.62.01.zip"},
+{"downloadType":"serverBedrockPreviewWindows","downloadUrl":"https://www.minecraft.net/bedrockdedicatedserver/bin-win-preview/bedrock-server-1.21.70.20.zip"},
+{"downloadType":"serverBedrockPreviewLinux","downloadUrl":"https://www.minecraft.net/bedrockdedicatedserver/bin-linux-preview/bedrock-server-1.21.70.20.zip"}
+]}}`
+
+func TestHTTPVersionSource(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{name: "links json", body: mojangLinks, want: []string{"1.21.62.01/stable", "1.21.70.20/preview"}},
+		{name: "html", body: `<a href="https://www.minecraft.net/bedrockdedicatedserver/bin-linux/bedrock-server-1.21.62.01.zip">Linux</a>
+<a href="https://www.minecraft.net/bedrockdedicatedserver/bin-win/bedrock-server-1.21.62.01.zip">Windows</a>`,
+			want: []string{"1.21.62.01/stable"}},
+		{name: "manifest json", body: `{"versions":[{"version":"1.21.50.10","url":"http://mirror/a.zip"},{"version":"1.21.60.21","channel":"preview"}]}`,
+			want: []string{"1.21.50.10/stable", "1.21.60.21/preview"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+			versions, err := NewHTTPVersionSource(srv.URL).Versions(context.Background())
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			got := make([]string, 0, len(versions))
+			for _, v := range versions {
+				got = append(got, v.Version+"/"+v.Channel)
+				if v.Source != "http" {
+					t.Errorf("unexpected source: %+v", v)
+				}
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPVersionSource_Status(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+	if _, err := NewHTTPVersionSource(srv.URL).Versions(context.Background()); err == nil {
+		t.Fatal("non 200 response should fail")
+	}
+}
+
+func TestVersionCatalog_Cache(t *testing.T) {
+	var hits, failing atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if failing.Load() == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte(mojangLinks))
+	}))
+	defer srv.Close()
+	catalog := NewVersionCatalog(time.Hour, NewHTTPVersionSource(srv.URL))
+	ctx := context.Background()
+
+	latest, err := catalog.Latest(ctx, ChannelStable)
+	if err != nil || latest.Version != "1.21.62.01" {
+		t.Fatalf("%+v %+v", latest, err)
+	}
+	preview, err := catalog.Latest(ctx, ChannelPreview)
+	if err != nil || preview.Version != "1.21.70.20" {
+		t.Fatalf("%+v %+v", preview, err)
+	}
+	if hits.Load() != 1 {
+		t.Fatalf("expected cached result, got %d requests", hits.Load())
+	}
+	// 刷新失败时沿用缓存
+	failing.Store(1)
+	versions, err := catalog.Versions(ctx, "", true)
+	if err != nil || len(versions) != 2 || hits.Load() != 2 {
+		t.Fatalf("%+v %+v %d", versions, err, hits.Load())
+	}
+	if _, err = catalog.Versions(ctx, "beta", false); err == nil {
+		t.Fatal("unknown channel should be rejected")
+	}
+	if _, err = NewVersionCatalog(time.Hour, NewHTTPVersionSource(srv.URL)).Versions(ctx, "", false); err == nil {
+		t.Fatal("failed source without cache should return an error")
+	}
+}
+
+func TestVersionCatalog_LocalSources(t *testing.T) {
+	mirror := t.TempDir()
+	for _, file := range []string{
+		"bedrock-server-1.21.62.01.zip",
+		"bedrock-server-1.21.50.10.zip",
+		"preview/bedrock-server-1.21.70.20.zip",
+		"notes.txt",
+	} {
+		if err := os.MkdirAll(path.Dir(path.Join(mirror, file)), os.ModePerm); err != nil {
+			t.Fatalf("%+v", err)
+		}
+		if err := os.WriteFile(path.Join(mirror, file), []byte(file), 0666); err != nil {
+			t.Fatalf("%+v", err)
+		}
+	}
+	manifest := path.Join(t.TempDir(), "versions.json")
+	err := os.WriteFile(manifest, []byte(`{"versions":[{"version":"1.21.62.01","url":"http://example/a.zip"},{"version":"1.21.44.01"}]}`), 0666)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	catalog := NewVersionCatalog(time.Hour, &MirrorVersionSource{Dir: mirror}, &ManifestVersionSource{File: manifest})
+
+	versions, err := catalog.Versions(context.Background(), ChannelStable, false)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	got := make([]string, 0, len(versions))
+	for _, v := range versions {
+		got = append(got, v.Version+"/"+v.Source)
+	}
+	if fmt.Sprint(got) != "[1.21.62.01/mirror 1.21.50.10/mirror 1.21.44.01/manifest]" {
+		t.Fatalf("unexpected versions: %v", got)
+	}
+
+	// 镜像中的版本直接复制，不经过下载器
+	manager := testManager(t)
+	manager.catalog = catalog
+	if err = manager.DownloadVersion("1.21.62.01"); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	data, err := os.ReadFile(manager.ZipFile("1.21.62.01"))
+	if err != nil || string(data) != "bedrock-server-1.21.62.01.zip" {
+		t.Fatalf("unexpected zip: %q %v", data, err)
+	}
+	if _, ok := manager.downloaders.Load("1.21.62.01"); ok {
+		t.Fatal("mirror copy should not create a downloader")
+	}
+	if _, err = os.Stat(manager.ZipFile("1.21.62.01") + ".part"); !os.IsNotExist(err) {
+		t.Fatal("temporary file should be renamed")
+	}
+}
+
+// slowVersionSource 在 release 关闭前阻塞的来源，用于测试获取期间不阻塞其他调用方
+type slowVersionSource struct {
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (source *slowVersionSource) Name() string {
+	return "slow"
+}
+
+func (source *slowVersionSource) Versions(context.Context) ([]VersionInfo, error) {
+	source.calls.Add(1)
+	<-source.release
+	return []VersionInfo{{Version: "1.21.62.01", Channel: ChannelStable, Source: "slow"}}, nil
+}
+
+func TestVersionCatalog_SlowSource(t *testing.T) {
+	source := &slowVersionSource{release: make(chan struct{})}
+	catalog := NewVersionCatalog(time.Hour, source)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, found := catalog.Lookup(ctx, "1.21.62.01"); found {
+		t.Fatal("lookup should give up before the source responds")
+	}
+	if time.Since(start) > time.Second {
+		t.Fatal("lookup should return once its context is done")
+	}
+	// 等待中的调用方共享同一次获取
+	results := make(chan bool, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, found := catalog.Lookup(context.Background(), "1.21.62.01")
+			results <- found
+		}()
+	}
+	close(source.release)
+	for i := 0; i < 2; i++ {
+		if !<-results {
+			t.Fatal("lookup should find the version once the source responds")
+		}
+	}
+	if calls := source.calls.Load(); calls != 1 {
+		t.Fatalf("expected a single fetch, got %d", calls)
+	}
+}
@@ -0,0 +1,325 @@
+package core
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/candbright/go-log/log"
+	"github.com/candbright/go-server/internal/mc-server/utils"
+	"github.com/pkg/errors"
+)
+
+const (
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+const (
+	// defaultServerPort bedrock 默认的 IPv4 端口，IPv6 端口为其后一位
+	defaultServerPort = 19132
+	// saveHoldTimeout 复制运行中服务器的世界前，等待存档落盘的最长时间
+	saveHoldTimeout = 30 * time.Second
+)
+
+// CloneJob 复制服务器的后台任务
+type CloneJob struct {
+	ID          string     `json:"id"`
+	SourceID    string     `json:"source_id"`
+	TargetID    string     `json:"target_id"`
+	State       string     `json:"state"`
+	TotalBytes  int64      `json:"total_bytes"`
+	CopiedBytes int64      `json:"copied_bytes"`
+	Percent     float64    `json:"percent"`
+	Error       string     `json:"error,omitempty"`
+	StartedAt   time.Time  `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+}
+
+type cloneJob struct {
+	mu  sync.Mutex
+	job CloneJob
+}
+
+func (j *cloneJob) snapshot() CloneJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	job := j.job
+	if job.TotalBytes > 0 {
+		job.Percent = float64(job.CopiedBytes) / float64(job.TotalBytes) * 100
+	}
+	return job
+}
+
+func (j *cloneJob) update(f func(job *CloneJob)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	f(&j.job)
+}
+
+func (j *cloneJob) finish(err error) {
+	j.update(func(job *CloneJob) {
+		now := time.Now()
+		job.FinishedAt = &now
+		job.State = JobDone
+		if err != nil {
+			job.State = JobFailed
+			job.Error = err.Error()
+		}
+	})
+}
+
+// skipSessionFiles 复制服务器目录时跳过进程会话产生的管道、日志和 pid 文件
+func skipSessionFiles(rel string, info os.FileInfo) bool {
+	if strings.ContainsAny(rel, `/\`) {
+		return false
+	}
+	switch rel {
+	case consoleLogFile, consoleLogFile + ".1", consoleInFile:
+		return true
+	}
+	return strings.HasPrefix(rel, "mc-") && (strings.HasSuffix(rel, ".log") || strings.HasSuffix(rel, ".log.1") || strings.HasSuffix(rel, ".pid"))
+}
+
+// CloneServer 以 source 为模板创建新服务器，复制版本、配置、白名单、权限和世界，并重新分配端口。
+// 复制在后台进行，返回的任务可通过 CloneJob 查询进度；复制完成前新服务器处于下载中状态，无法启动。
+func (manager *ServerManager) CloneServer(sourceID, name string) (CloneJob, error) {
+	source, err := manager.GetServer(sourceID)
+	if err != nil {
+		return CloneJob{}, err
+	}
+	if !source.ServerExist() {
+		return CloneJob{}, errors.Errorf("server [%s] has no installed version", sourceID)
+	}
+	if strings.TrimSpace(name) == "" {
+		name = source.GetServerName() + " (copy)"
+	}
+	if _, err = validServerName(name); err != nil {
+		return CloneJob{}, err
+	}
+
+	manager.mu.Lock()
+	id := manager.nextServerID()
+	rootDir := path.Join(manager.rootDir, "server-"+id)
+	target, err := manager.reserveClone(id, rootDir, name, source.GetVersion())
+	if err != nil {
+		manager.mu.Unlock()
+		_ = os.RemoveAll(rootDir)
+		return CloneJob{}, err
+	}
+	manager.servers.Store(id, target)
+	manager.mu.Unlock()
+
+	job := &cloneJob{job: CloneJob{
+		ID:        utils.RandomString(8, utils.AlphaNumCharset),
+		SourceID:  sourceID,
+		TargetID:  id,
+		State:     JobRunning,
+		StartedAt: time.Now(),
+	}}
+	manager.cloneJobs.Store(job.job.ID, job)
+	go manager.runClone(job, source, target)
+	return job.snapshot(), nil
+}
+
+// reserveClone 创建新服务器目录，写入下载锁文件使其在复制完成前不可用
+func (manager *ServerManager) reserveClone(id, rootDir, name, version string) (*Server, error) {
+	err := os.MkdirAll(rootDir, os.ModePerm)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	err = os.WriteFile(path.Join(rootDir, "downloading"), nil, 0666)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	err = os.WriteFile(path.Join(rootDir, "version"), []byte(version), 0666)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return manager.initServer(id, rootDir, name, CreateServerConfig{})
+}
+
+func (manager *ServerManager) runClone(job *cloneJob, source, target *Server) {
+	logger := log.WithField("server_id", source.id).WithField("target_id", target.id)
+	err := manager.copyServer(job, source, target)
+	if err != nil {
+		logger.WithError(err).Error("clone server failed")
+		manager.servers.Delete(target.id)
+		_ = os.RemoveAll(target.rootDir)
+	} else {
+		logger.Info("server cloned")
+	}
+	job.finish(err)
+}
+
+func (manager *ServerManager) copyServer(job *cloneJob, source, target *Server) error {
+	source.cloning.Add(1)
+	defer source.cloning.Add(-1)
+	release, lengths, err := source.holdSave()
+	if err != nil {
+		return err
+	}
+	total, err := dirSize(source.WorkDir(), skipSessionFiles)
+	if err != nil {
+		release()
+		return err
+	}
+	job.update(func(job *CloneJob) {
+		job.TotalBytes = total
+	})
+	err = copyDir(source.WorkDir(), target.WorkDir(), skipSessionFiles, func(n int64) {
+		job.update(func(job *CloneJob) {
+			job.CopiedBytes += n
+		})
+	})
+	release()
+	if err != nil {
+		return err
+	}
+	err = truncateSavedFiles(target.WorldsDir(), lengths)
+	if err != nil {
+		return err
+	}
+
+	v4, v6 := freePortPair(manager.usedPorts(target.id))
+	if Exists(path.Join(target.WorkDir(), "server.properties")) {
+		sp := NewServerProperties(ServerPropertiesConfig{
+			Version: target.GetVersion(),
+			RootDir: target.WorkDir(),
+		})
+		err = sp.SetAll(map[string]string{
+			"server-port":   strconv.Itoa(v4),
+			"server-portv6": strconv.Itoa(v6),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	err = os.Remove(target.DownloadingFilePath())
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return target.Reload()
+}
+
+// CloneJob 返回复制任务的当前进度
+func (manager *ServerManager) CloneJob(id string) (CloneJob, error) {
+	value, ok := manager.cloneJobs.Load(id)
+	if !ok {
+		return CloneJob{}, errors.Errorf("clone job [%s] not found", id)
+	}
+	return value.(*cloneJob).snapshot(), nil
+}
+
+// usedPorts 返回除 exclude 外所有服务器在 server.properties 中配置的端口
+func (manager *ServerManager) usedPorts(exclude string) map[int]bool {
+	used := make(map[int]bool)
+	for id, server := range manager.GetServers() {
+		if id == exclude {
+			continue
+		}
+		sp, err := server.ServerProperties()
+		if err != nil {
+			continue
+		}
+		for _, key := range []string{"server-port", "server-portv6"} {
+			if port, err := strconv.Atoi(sp.Get(key)); err == nil {
+				used[port] = true
+			}
+		}
+	}
+	return used
+}
+
+// freePortPair 从默认端口开始查找一对未被占用的相邻端口，分别用于 IPv4 和 IPv6
+func freePortPair(used map[int]bool) (int, int) {
+	for port := defaultServerPort; port < 65535; port += 2 {
+		if !used[port] && !used[port+1] {
+			return port, port + 1
+		}
+	}
+	return defaultServerPort, defaultServerPort + 1
+}
+
+// holdSave 服务器运行时暂停自动存档并等待数据落盘，以便安全复制世界文件。
+// 返回的 release 用于恢复存档，服务器未运行时无需暂停。
+// 暂停期间服务器仍会向 LevelDB 文件追加数据，返回的 lengths 为 save query 列出的各文件
+// 在存档点的长度，键为相对 worlds 目录的路径，复制后需按其截断
+func (server *Server) holdSave() (release func(), lengths map[string]int64, err error) {
+	if !server.Active() {
+		return func() {}, nil, nil
+	}
+	if err = server.execCmd("save", "hold"); err != nil {
+		return nil, nil, err
+	}
+	var once sync.Once
+	release = func() {
+		once.Do(func() {
+			if err := server.execCmd("save", "resume"); err != nil {
+				log.WithError(err).WithField("server_id", server.id).Warn("resume saving failed")
+			}
+		})
+	}
+	deadline := time.Now().Add(saveHoldTimeout)
+	for time.Now().Before(deadline) {
+		lines, err := server.currentProcess().ExecCmdOutput(DefaultCommandWindow, "save", "query")
+		if err != nil {
+			release()
+			return nil, nil, err
+		}
+		if lengths, ok := parseSaveQuery(lines); ok {
+			return release, lengths, nil
+		}
+		time.Sleep(time.Second)
+	}
+	release()
+	return nil, nil, errors.New("timed out waiting for the world to be saved")
+}
+
+// parseSaveQuery 解析 save query 的输出。存档完成时 "Data saved" 之后的行为
+// 逗号分隔的 "<相对 worlds 目录的路径>:<长度>" 列表，存档未完成时返回 false
+func parseSaveQuery(lines []string) (map[string]int64, bool) {
+	saved := false
+	lengths := make(map[string]int64)
+	for _, line := range lines {
+		if !saved {
+			saved = strings.Contains(line, "Data saved")
+			continue
+		}
+		for _, item := range strings.Split(line, ", ") {
+			i := strings.LastIndex(item, ":")
+			if i <= 0 {
+				continue
+			}
+			n, err := strconv.ParseInt(strings.TrimSpace(item[i+1:]), 10, 64)
+			if err != nil {
+				continue
+			}
+			lengths[filepath.ToSlash(strings.TrimSpace(item[:i]))] = n
+		}
+	}
+	return lengths, saved
+}
+
+// truncateSavedFiles 将复制到 worldsDir 中的文件截断为存档点的长度，丢弃暂停存档后追加的数据
+func truncateSavedFiles(worldsDir string, lengths map[string]int64) error {
+	for rel, n := range lengths {
+		file := filepath.Join(worldsDir, filepath.FromSlash(rel))
+		if r, err := filepath.Rel(worldsDir, file); err != nil || strings.HasPrefix(r, "..") {
+			continue
+		}
+		info, err := os.Stat(file)
+		if err != nil || info.Size() <= n {
+			continue
+		}
+		if err = os.Truncate(file, n); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package core
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFreePortPair(t *testing.T) {
+	v4, v6 := freePortPair(map[int]bool{19132: true, 19133: true, 19135: true})
+	if v4 != 19136 || v6 != 19137 {
+		t.Fatalf("unexpected ports: %d %d", v4, v6)
+	}
+}
+
+func TestParseSaveQuery(t *testing.T) {
+	if _, ok := parseSaveQuery([]string{"Previous save has not completed."}); ok {
+		t.Fatal("save should not be reported as completed")
+	}
+	lengths, ok := parseSaveQuery([]string{
+		"[INFO] Data saved. Files are now ready to be copied.",
+		"Bedrock level/db/000005.ldb:1024, Bedrock level/level.dat:2412",
+	})
+	if !ok || len(lengths) != 2 || lengths["Bedrock level/db/000005.ldb"] != 1024 || lengths["Bedrock level/level.dat"] != 2412 {
+		t.Fatalf("unexpected lengths: %v %v", lengths, ok)
+	}
+}
+
+func TestServerManager_CloneServer(t *testing.T) {
+	source := testFakeServer(t)
+	data, err := os.ReadFile("example/server.properties")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err = os.WriteFile(path.Join(source.WorkDir(), "server.properties"), data, 0666); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	world := path.Join(source.WorldsDir(), "Bedrock level", "db")
+	if err = os.MkdirAll(world, os.ModePerm); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err = os.WriteFile(path.Join(world, "000005.ldb"), []byte(strings.Repeat("x", 1024)), 0666); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	manager := testManager(t, source)
+	// 运行中的服务器在复制期间暂停存档
+	if err = source.Start(); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if _, err = source.process.WaitReady(5 * time.Second); err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	job, err := manager.CloneServer(source.id, "")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	waitFor(t, 10*time.Second, func() bool {
+		job, _ = manager.CloneJob(job.ID)
+		return job.State != JobRunning
+	})
+	if job.State != JobDone || job.Percent != 100 {
+		t.Fatalf("unexpected job: %+v", job)
+	}
+	for _, command := range []string{"[INFO] save hold", "[INFO] save resume"} {
+		found := false
+		for _, line := range source.Console().Lines(0) {
+			found = found || line.Text == command
+		}
+		if !found {
+			t.Errorf("expected command %q", command)
+		}
+	}
+
+	target, err := manager.GetServer(job.TargetID)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	t.Cleanup(func() {
+		_ = target.Delete()
+	})
+	if !target.ServerExist() || target.GetVersion() != source.GetVersion() {
+		t.Fatal("cloned server should exist with the same version")
+	}
+	if name := target.GetServerName(); name != "unnamed server (copy)" {
+		t.Fatalf("unexpected name: %s", name)
+	}
+	info, err := os.Stat(path.Join(target.WorldsDir(), "Bedrock level", "db", "000005.ldb"))
+	if err != nil {
+		t.Fatalf("world should be copied: %+v", err)
+	}
+	// 暂停存档后追加的数据不应被复制
+	if source, err := os.Stat(path.Join(world, "000005.ldb")); err != nil || source.Size() <= 1024 {
+		t.Fatalf("fake server should append to the source db file: %v", err)
+	}
+	if info.Size() != 1024 {
+		t.Fatalf("copied db file should be truncated to the saved length, got %d", info.Size())
+	}
+	if Exists(path.Join(target.WorkDir(), consoleLogFile)) {
+		t.Fatal("session files should not be copied")
+	}
+	sp, err := target.ServerProperties()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if sp.Get("server-port") != "19134" || sp.Get("server-portv6") != "19135" {
+		t.Fatalf("ports should be reassigned, got %s %s", sp.Get("server-port"), sp.Get("server-portv6"))
+	}
+	if err = target.Start(); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if _, err = target.process.WaitReady(5 * time.Second); err != nil {
+		t.Fatalf("%+v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultCommandWindow 等待命令回显的默认时间窗口
+	DefaultCommandWindow = time.Second
+	// MaxCommandWindow 允许调用方指定的最长时间窗口
+	MaxCommandWindow = 30 * time.Second
+	// commandQuietPeriod 收到回显后若在该时间内没有新输出，则认为命令输出已结束
+	commandQuietPeriod = 250 * time.Millisecond
+)
+
+// commandFailurePatterns bedrock 执行命令失败时常见的输出片段
+var commandFailurePatterns = []string{
+	"Unknown command",
+	"Syntax error",
+	"Incorrect argument",
+	"No targets matched selector",
+	"Player not found",
+	"Could not find player",
+	"Player not in allowlist",
+	"You do not have permission",
+}
+
+// asyncOutputPatterns 与命令无关、随时可能出现在控制台的输出片段，收集命令输出时忽略
+var asyncOutputPatterns = []string{
+	"Player connected:",
+	"Player disconnected:",
+	"Player Spawned:",
+	"AutoCompaction",
+}
+
+// asyncOutput 判断输出行是否为玩家进出、自动整理存档等异步日志
+func asyncOutput(line string) bool {
+	for _, pattern := range asyncOutputPatterns {
+		if strings.Contains(line, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// CommandError 表示服务器拒绝执行命令
+type CommandError struct {
+	Command string
+	Line    string
+}
+
+func (err CommandError) Error() string {
+	return fmt.Sprintf("command [%s] failed: %s", err.Command, err.Line)
+}
+
+// commandFailure 返回输出中第一条匹配失败特征的行
+func commandFailure(lines []string) (string, bool) {
+	for _, line := range lines {
+		for _, pattern := range commandFailurePatterns {
+			if strings.Contains(line, pattern) {
+				return line, true
+			}
+		}
+	}
+	return "", false
+}
+
+// ExecCmdOutput 执行命令并收集其后 window 时间内的输出行。
+// 收到输出后若持续 commandQuietPeriod 没有新行则提前返回；输出命中失败特征时返回 CommandError。
+// bedrock 的控制台输出不带命令标识，无法区分回显属于哪条命令：已知的异步日志会被过滤，
+// 但窗口内其他来源的输出（例如同时执行的另一条命令的回显）仍可能混入结果。
+func (p *Process) ExecCmdOutput(window time.Duration, arg ...string) ([]string, error) {
+	if window <= 0 {
+		window = DefaultCommandWindow
+	}
+	if window > MaxCommandWindow {
+		window = MaxCommandWindow
+	}
+	command := strings.Join(arg, " ")
+	// 先订阅再发送命令，保证不漏掉回显
+	lines, cancel := p.console.Subscribe()
+	defer cancel()
+	err := p.ExecCmd(arg...)
+	if err != nil {
+		return nil, err
+	}
+
+	output := make([]string, 0)
+	deadline := time.NewTimer(window)
+	defer deadline.Stop()
+	var quiet <-chan time.Time
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				return output, nil
+			}
+			// tmux 等终端后端会回显输入的命令本身，异步日志不属于命令的输出
+			if strings.TrimSpace(line.Text) == command || asyncOutput(line.Text) {
+				continue
+			}
+			output = append(output, line.Text)
+			quiet = time.After(commandQuietPeriod)
+			continue
+		case <-quiet:
+		case <-deadline.C:
+		}
+		break
+	}
+	if failure, ok := commandFailure(output); ok {
+		return output, CommandError{Command: command, Line: failure}
+	}
+	return output, nil
+}
@@ -0,0 +1,64 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestProcess_ExecCmdOutput(t *testing.T) {
+	process := testProcess(t, BackendNative)
+	err := process.Start()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	waitStarted(t, process)
+	lines, err := process.ExecCmdOutput(2*time.Second, "list")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if len(lines) != 1 || lines[0] != "[INFO] list" {
+		t.Fatalf("unexpected output: %q", lines)
+	}
+}
+
+func TestProcess_ExecCmdOutputIgnoresAsyncLines(t *testing.T) {
+	process := testProcess(t, BackendNative)
+	err := process.Start()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	waitStarted(t, process)
+	lines, err := process.ExecCmdOutput(2*time.Second, "noisy")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if len(lines) != 1 || lines[0] != "[INFO] noisy" {
+		t.Fatalf("unexpected output: %q", lines)
+	}
+}
+
+func TestProcess_ExecCmdOutputFailure(t *testing.T) {
+	process := testProcess(t, BackendNative)
+	err := process.Start()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	waitStarted(t, process)
+	lines, err := process.ExecCmdOutput(2*time.Second, "bogus")
+	var cmdErr CommandError
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("expected CommandError, got %v", err)
+	}
+	if len(lines) != 1 || cmdErr.Command != "bogus" {
+		t.Fatalf("unexpected output %q for error %v", lines, cmdErr)
+	}
+}
+
+func TestProcess_ExecCmdOutputNotRunning(t *testing.T) {
+	process := testProcess(t, BackendNative)
+	if _, err := process.ExecCmdOutput(time.Second, "list"); err == nil {
+		t.Fatal("exec on a stopped process should fail")
+	}
+}
@@ -0,0 +1,134 @@
+package core
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	DefaultConsoleSize = 1000
+	// consoleSubscriberBuffer 订阅者通道缓冲，订阅者消费过慢时新行会被丢弃，不会阻塞服务器输出
+	consoleSubscriberBuffer = 256
+)
+
+type ConsoleLine struct {
+	Seq  uint64    `json:"seq"`
+	Time time.Time `json:"time"`
+	Text string    `json:"text"`
+}
+
+// Console 以环形缓冲保存服务器最近的输出行，并向订阅者推送新行
+type Console struct {
+	mu      sync.Mutex
+	lines   []ConsoleLine
+	start   int
+	count   int
+	nextSeq uint64
+	subs    map[chan ConsoleLine]struct{}
+	// handlers 同步处理每一行输出，不会丢行
+	handlers []func(line ConsoleLine)
+}
+
+func NewConsole(size int) *Console {
+	if size <= 0 {
+		size = DefaultConsoleSize
+	}
+	return &Console{
+		lines:   make([]ConsoleLine, size),
+		nextSeq: 1,
+		subs:    make(map[chan ConsoleLine]struct{}),
+	}
+}
+
+// Write 追加一行输出
+func (c *Console) Write(text string) ConsoleLine {
+	c.mu.Lock()
+	line := ConsoleLine{
+		Seq:  c.nextSeq,
+		Time: time.Now(),
+		Text: text,
+	}
+	c.nextSeq++
+	size := len(c.lines)
+	if c.count < size {
+		c.lines[(c.start+c.count)%size] = line
+		c.count++
+	} else {
+		c.lines[c.start] = line
+		c.start = (c.start + 1) % size
+	}
+	for sub := range c.subs {
+		select {
+		case sub <- line:
+		default:
+		}
+	}
+	handlers := c.handlers
+	c.mu.Unlock()
+	// 在锁外回调，处理函数中可以继续读取控制台
+	for _, handler := range handlers {
+		handler(line)
+	}
+	return line
+}
+
+// OnWrite 注册输出处理函数，每写入一行同步回调一次，用于解析日志事件
+func (c *Console) OnWrite(handler func(line ConsoleLine)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.handlers = append(c.handlers, handler)
+}
+
+// LastSeq 返回最近一行的序号，尚无输出时返回 0
+func (c *Console) LastSeq() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.nextSeq - 1
+}
+
+// Lines 返回最近的 n 行，n <= 0 时返回缓冲中的全部行
+func (c *Console) Lines(n int) []ConsoleLine {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if n <= 0 || n > c.count {
+		n = c.count
+	}
+	result := make([]ConsoleLine, 0, n)
+	size := len(c.lines)
+	for i := c.count - n; i < c.count; i++ {
+		result = append(result, c.lines[(c.start+i)%size])
+	}
+	return result
+}
+
+// Since 返回序号大于 seq 的所有缓冲行
+func (c *Console) Since(seq uint64) []ConsoleLine {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result := make([]ConsoleLine, 0)
+	size := len(c.lines)
+	for i := 0; i < c.count; i++ {
+		line := c.lines[(c.start+i)%size]
+		if line.Seq > seq {
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+// Subscribe 订阅新输出行，使用完毕后必须调用返回的取消函数
+func (c *Console) Subscribe() (<-chan ConsoleLine, func()) {
+	ch := make(chan ConsoleLine, consoleSubscriberBuffer)
+	c.mu.Lock()
+	c.subs[ch] = struct{}{}
+	c.mu.Unlock()
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			c.mu.Lock()
+			delete(c.subs, ch)
+			c.mu.Unlock()
+			close(ch)
+		})
+	}
+}
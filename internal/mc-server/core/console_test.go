@@ -0,0 +1,72 @@
+package core
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestConsole_Lines(t *testing.T) {
+	console := NewConsole(3)
+	if lines := console.Lines(10); len(lines) != 0 {
+		t.Fatalf("expected empty console, got %d lines", len(lines))
+	}
+	for i := 1; i <= 5; i++ {
+		console.Write(fmt.Sprintf("line %d", i))
+	}
+	lines := console.Lines(0)
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d", len(lines))
+	}
+	for i, line := range lines {
+		if expected := fmt.Sprintf("line %d", i+3); line.Text != expected {
+			t.Errorf("expected %q, got %q", expected, line.Text)
+		}
+	}
+	if lines = console.Lines(2); len(lines) != 2 || lines[0].Text != "line 4" {
+		t.Fatalf("unexpected last 2 lines: %+v", lines)
+	}
+	if seq := console.LastSeq(); seq != 5 {
+		t.Fatalf("expected last seq 5, got %d", seq)
+	}
+}
+
+func TestConsole_Since(t *testing.T) {
+	console := NewConsole(10)
+	for i := 1; i <= 4; i++ {
+		console.Write(fmt.Sprintf("line %d", i))
+	}
+	lines := console.Since(2)
+	if len(lines) != 2 || lines[0].Seq != 3 || lines[1].Seq != 4 {
+		t.Fatalf("unexpected lines since 2: %+v", lines)
+	}
+}
+
+func TestConsole_Subscribe(t *testing.T) {
+	console := NewConsole(10)
+	lines, cancel := console.Subscribe()
+	console.Write("hello")
+	select {
+	case line := <-lines:
+		if line.Text != "hello" {
+			t.Fatalf("expected hello, got %q", line.Text)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("subscriber did not receive line")
+	}
+	cancel()
+	cancel()
+	console.Write("after cancel")
+	if _, ok := <-lines; ok {
+		t.Fatal("channel should be closed after cancel")
+	}
+}
+
+func TestProcess_ConsoleCapture(t *testing.T) {
+	process := testProcess(t, BackendNative)
+	err := process.Start()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	waitStarted(t, process)
+}
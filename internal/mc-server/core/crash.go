@@ -0,0 +1,217 @@
+package core
+
+import (
+	"sync"
+	"time"
+
+	"github.com/candbright/go-log/log"
+	"github.com/candbright/go-server/pkg/dw"
+	"github.com/pkg/errors"
+)
+
+const (
+	RestartNever     = "never"
+	RestartOnFailure = "on-failure"
+	RestartAlways    = "always"
+)
+
+const (
+	// maxCrashEvents 每个服务器保留的崩溃记录数量
+	maxCrashEvents = 50
+	// crashLastLines 崩溃记录中保留的控制台行数
+	crashLastLines = 20
+
+	crashReasonNever       = "restart policy is never"
+	crashReasonCleanExit   = "process exited with code 0"
+	crashReasonTooManyRuns = "too many restarts in window"
+)
+
+// RestartPolicy 服务器进程意外退出后的自动重启策略
+type RestartPolicy struct {
+	// Mode 重启模式：never 不重启；on-failure 退出码非 0 时重启；always 任何意外退出都重启
+	Mode string `json:"mode"`
+	// MaxRestarts 在 WindowSeconds 时间窗口内允许的最大重启次数，超过后放弃重启
+	MaxRestarts   int `json:"max_restarts"`
+	WindowSeconds int `json:"window_seconds"`
+	// BackoffSeconds 首次重启前的等待时间，之后每次翻倍，最长 MaxBackoffSeconds
+	BackoffSeconds    int `json:"backoff_seconds"`
+	MaxBackoffSeconds int `json:"max_backoff_seconds"`
+}
+
+func DefaultRestartPolicy() RestartPolicy {
+	return RestartPolicy{
+		Mode:              RestartOnFailure,
+		MaxRestarts:       3,
+		WindowSeconds:     600,
+		BackoffSeconds:    5,
+		MaxBackoffSeconds: 300,
+	}
+}
+
+func (policy RestartPolicy) Validate() error {
+	switch policy.Mode {
+	case RestartNever, RestartOnFailure, RestartAlways:
+	default:
+		return errors.Errorf("unsupported restart mode [%s]", policy.Mode)
+	}
+	if policy.MaxRestarts < 0 || policy.WindowSeconds <= 0 ||
+		policy.BackoffSeconds < 0 || policy.MaxBackoffSeconds < policy.BackoffSeconds {
+		return errors.New("invalid restart policy limits")
+	}
+	return nil
+}
+
+func (policy RestartPolicy) window() time.Duration {
+	return time.Duration(policy.WindowSeconds) * time.Second
+}
+
+// backoff 返回窗口内第 n 次（从 0 开始）重启前的等待时间
+func (policy RestartPolicy) backoff(n int) time.Duration {
+	delay := time.Duration(policy.BackoffSeconds) * time.Second
+	max := time.Duration(policy.MaxBackoffSeconds) * time.Second
+	for i := 0; i < n && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
+
+// CrashEvent 一次进程意外退出的记录
+type CrashEvent struct {
+	Time      time.Time `json:"time"`
+	ExitCode  int       `json:"exit_code"`
+	LastLines []string  `json:"last_lines"`
+	// Restarted 是否按策略安排了重启
+	Restarted bool `json:"restarted"`
+	// Reason 未重启时的原因
+	Reason string `json:"reason,omitempty"`
+}
+
+// CrashStats 用于展示的崩溃统计，如“10 分钟内崩溃 3 次”
+type CrashStats struct {
+	Policy        RestartPolicy `json:"policy"`
+	WindowSeconds int           `json:"window_seconds"`
+	// RecentCount 时间窗口内的崩溃次数
+	RecentCount int         `json:"recent_count"`
+	Total       int         `json:"total"`
+	LastCrash   *CrashEvent `json:"last_crash,omitempty"`
+	// GaveUp 最近一次崩溃因超过重启次数限制而放弃重启
+	GaveUp bool `json:"gave_up"`
+}
+
+// CrashMonitor 记录服务器的崩溃事件并根据重启策略决定是否重启
+type CrashMonitor struct {
+	mu       sync.Mutex
+	policy   *dw.DataWriter[RestartPolicy]
+	events   *dw.DataWriter[[]CrashEvent]
+	restarts []time.Time
+}
+
+func NewCrashMonitor(policyPath, eventsPath string) (*CrashMonitor, error) {
+	policy, err := dw.JsonOrDefault[RestartPolicy](policyPath, DefaultRestartPolicy())
+	if err != nil {
+		return nil, err
+	}
+	events, err := dw.JsonOrDefault[[]CrashEvent](eventsPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &CrashMonitor{
+		policy: policy,
+		events: events,
+	}, nil
+}
+
+func (m *CrashMonitor) Policy() RestartPolicy {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.policy.Data
+}
+
+func (m *CrashMonitor) SetPolicy(policy RestartPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.policy.Data = policy
+	return m.policy.Write()
+}
+
+func (m *CrashMonitor) Events() []CrashEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	events := make([]CrashEvent, len(m.events.Data))
+	copy(events, m.events.Data)
+	return events
+}
+
+// Record 记录一次意外退出，返回是否需要重启以及重启前的等待时间
+func (m *CrashMonitor) Record(exit ProcessExit, lastLines []string) (bool, time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	policy := m.policy.Data
+	event := CrashEvent{
+		Time:      exit.Time,
+		ExitCode:  exit.ExitCode,
+		LastLines: lastLines,
+	}
+
+	// 只保留时间窗口内的重启记录
+	recent := m.restarts[:0]
+	for _, t := range m.restarts {
+		if exit.Time.Sub(t) < policy.window() {
+			recent = append(recent, t)
+		}
+	}
+	m.restarts = recent
+
+	var delay time.Duration
+	switch {
+	case policy.Mode == RestartNever:
+		event.Reason = crashReasonNever
+	case policy.Mode == RestartOnFailure && exit.ExitCode == 0:
+		event.Reason = crashReasonCleanExit
+	case len(m.restarts) >= policy.MaxRestarts:
+		event.Reason = crashReasonTooManyRuns
+	default:
+		delay = policy.backoff(len(m.restarts))
+		m.restarts = append(m.restarts, exit.Time.Add(delay))
+		event.Restarted = true
+	}
+
+	m.events.Data = append(m.events.Data, event)
+	if len(m.events.Data) > maxCrashEvents {
+		m.events.Data = m.events.Data[len(m.events.Data)-maxCrashEvents:]
+	}
+	if err := m.events.Write(); err != nil {
+		log.WithError(err).Error("save crash events failed")
+	}
+	return event.Restarted, delay
+}
+
+func (m *CrashMonitor) Stats() CrashStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	policy := m.policy.Data
+	stats := CrashStats{
+		Policy:        policy,
+		WindowSeconds: policy.WindowSeconds,
+		Total:         len(m.events.Data),
+	}
+	now := time.Now()
+	for i := range m.events.Data {
+		event := m.events.Data[i]
+		if now.Sub(event.Time) < policy.window() {
+			stats.RecentCount++
+		}
+	}
+	if n := len(m.events.Data); n > 0 {
+		last := m.events.Data[n-1]
+		stats.LastCrash = &last
+		stats.GaveUp = !last.Restarted && last.Reason == crashReasonTooManyRuns
+	}
+	return stats
+}
@@ -0,0 +1,123 @@
+package core
+
+import (
+	"path"
+	"testing"
+	"time"
+)
+
+func testCrashMonitor(t *testing.T, policy RestartPolicy) *CrashMonitor {
+	dir := t.TempDir()
+	m, err := NewCrashMonitor(path.Join(dir, "restart_policy.json"), path.Join(dir, "crashes.json"))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	err = m.SetPolicy(policy)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	return m
+}
+
+func TestCrashMonitor_Record(t *testing.T) {
+	policy := DefaultRestartPolicy()
+	policy.MaxRestarts = 2
+	m := testCrashMonitor(t, policy)
+	now := time.Now()
+
+	restart, delay := m.Record(ProcessExit{Time: now, ExitCode: 1}, []string{"boom"})
+	if !restart || delay != 5*time.Second {
+		t.Fatalf("expected restart after 5s, got %v %v", restart, delay)
+	}
+	restart, delay = m.Record(ProcessExit{Time: now.Add(10 * time.Second), ExitCode: 1}, nil)
+	if !restart || delay != 10*time.Second {
+		t.Fatalf("expected restart after 10s, got %v %v", restart, delay)
+	}
+	restart, _ = m.Record(ProcessExit{Time: now.Add(30 * time.Second), ExitCode: 1}, nil)
+	if restart {
+		t.Fatal("expected no restart after exceeding max restarts")
+	}
+	stats := m.Stats()
+	if stats.RecentCount != 3 || stats.Total != 3 || !stats.GaveUp {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if events := m.Events(); events[0].LastLines[0] != "boom" {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+
+	// 超出时间窗口后重新允许重启
+	restart, delay = m.Record(ProcessExit{Time: now.Add(time.Hour), ExitCode: 1}, nil)
+	if !restart || delay != 5*time.Second {
+		t.Fatalf("expected restart after window, got %v %v", restart, delay)
+	}
+}
+
+func TestCrashMonitor_Mode(t *testing.T) {
+	policy := DefaultRestartPolicy()
+	m := testCrashMonitor(t, policy)
+	if restart, _ := m.Record(ProcessExit{Time: time.Now(), ExitCode: 0}, nil); restart {
+		t.Fatal("on-failure should not restart a clean exit")
+	}
+	policy.Mode = RestartAlways
+	_ = m.SetPolicy(policy)
+	if restart, _ := m.Record(ProcessExit{Time: time.Now(), ExitCode: 0}, nil); !restart {
+		t.Fatal("always should restart a clean exit")
+	}
+	policy.Mode = RestartNever
+	_ = m.SetPolicy(policy)
+	if restart, _ := m.Record(ProcessExit{Time: time.Now(), ExitCode: 1}, nil); restart {
+		t.Fatal("never should not restart")
+	}
+	if err := m.SetPolicy(RestartPolicy{Mode: "sometimes"}); err == nil {
+		t.Fatal("invalid mode should be rejected")
+	}
+}
+
+func TestCrashMonitor_Persist(t *testing.T) {
+	dir := t.TempDir()
+	policyPath, eventsPath := path.Join(dir, "restart_policy.json"), path.Join(dir, "crashes.json")
+	m, err := NewCrashMonitor(policyPath, eventsPath)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	m.Record(ProcessExit{Time: time.Now(), ExitCode: 1}, nil)
+	m, err = NewCrashMonitor(policyPath, eventsPath)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if len(m.Events()) != 1 {
+		t.Fatal("crash events should survive reload")
+	}
+}
+
+func TestServer_RestartAfterCrash(t *testing.T) {
+	server := testFakeServer(t)
+	policy := DefaultRestartPolicy()
+	policy.BackoffSeconds = 0
+	err := server.SetRestartPolicy(policy)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	err = server.Start()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	waitStarted(t, server.process)
+	err = server.process.ExecCmd("crash")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	waitFor(t, 5*time.Second, func() bool {
+		return server.CrashStats().Total == 1 && server.process.Active()
+	})
+	if last := server.CrashStats().LastCrash; last.ExitCode != 3 || !last.Restarted {
+		t.Fatalf("unexpected crash event: %+v", last)
+	}
+	_, err = server.Shutdown()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if server.CrashStats().Total != 1 {
+		t.Fatal("requested shutdown should not be recorded as crash")
+	}
+}
@@ -0,0 +1,136 @@
+package core
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// cronField 描述 cron 表达式中一个字段的取值范围
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 6},
+}
+
+// CronSchedule 标准 5 段 cron 表达式（分 时 日 月 周），支持 *、列表、范围和步长，周日为 0 或 7
+type CronSchedule struct {
+	expr string
+	sets [5]map[int]bool
+	// domAny、dowAny 日和周是否为 *，两者都受限时任一匹配即可，与 crontab 一致
+	domAny bool
+	dowAny bool
+}
+
+// ParseCron 解析 cron 表达式
+func ParseCron(expr string) (*CronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, errors.Errorf("cron expression [%s] must have 5 fields", expr)
+	}
+	schedule := &CronSchedule{
+		expr:   strings.Join(fields, " "),
+		domAny: fields[2] == "*",
+		dowAny: fields[4] == "*",
+	}
+	for i, field := range cronFields {
+		set, err := parseCronField(fields[i], field)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cron expression [%s]", expr)
+		}
+		schedule.sets[i] = set
+	}
+	// 周日既可以写作 0 也可以写作 7
+	if schedule.sets[4][7] {
+		schedule.sets[4][0] = true
+	}
+	return schedule, nil
+}
+
+func parseCronField(value string, field cronField) (map[int]bool, error) {
+	set := make(map[int]bool)
+	max := field.max
+	if field.name == "day of week" {
+		max = 7
+	}
+	for _, part := range strings.Split(value, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step <= 0 {
+				return nil, errors.Errorf("invalid step in %s field [%s]", field.name, part)
+			}
+			rangePart = part[:i]
+		}
+		lo, hi := field.min, max
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			lo, err = strconv.Atoi(bounds[0])
+			if err != nil {
+				return nil, errors.Errorf("invalid %s field [%s]", field.name, part)
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				hi, err = strconv.Atoi(bounds[1])
+				if err != nil {
+					return nil, errors.Errorf("invalid %s field [%s]", field.name, part)
+				}
+			} else if step > 1 {
+				// "5/15" 表示从 5 开始每 15 个单位
+				hi = max
+			}
+		}
+		if lo < field.min || hi > max || lo > hi {
+			return nil, errors.Errorf("%s field [%s] out of range %d-%d", field.name, part, field.min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+	return set, nil
+}
+
+func (schedule *CronSchedule) String() string {
+	return schedule.expr
+}
+
+// Matches 判断 t 所在的分钟是否命中表达式
+func (schedule *CronSchedule) Matches(t time.Time) bool {
+	if !schedule.sets[0][t.Minute()] || !schedule.sets[1][t.Hour()] || !schedule.sets[3][int(t.Month())] {
+		return false
+	}
+	dom := schedule.sets[2][t.Day()]
+	dow := schedule.sets[4][int(t.Weekday())]
+	switch {
+	case schedule.domAny && schedule.dowAny:
+		return true
+	case schedule.domAny:
+		return dow
+	case schedule.dowAny:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+// Next 返回 t 之后第一个命中表达式的时间（精确到分钟），一年内没有命中时返回零值
+func (schedule *CronSchedule) Next(t time.Time) time.Time {
+	next := t.Truncate(time.Minute).Add(time.Minute)
+	for limit := next.AddDate(1, 0, 0); next.Before(limit); next = next.Add(time.Minute) {
+		if schedule.Matches(next) {
+			return next
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,46 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	for _, expr := range []string{"* * * * *", "*/15 9-17 * * 1-5", "0 4 1,15 * 7", "5/20 * * * *"} {
+		if _, err := ParseCron(expr); err != nil {
+			t.Errorf("ParseCron(%q): %v", expr, err)
+		}
+	}
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) should fail", expr)
+		}
+	}
+}
+
+func TestCronSchedule_Next(t *testing.T) {
+	base := time.Date(2024, 5, 10, 10, 7, 30, 0, time.Local) // 周五
+	cases := []struct {
+		expr     string
+		expected time.Time
+	}{
+		{"* * * * *", time.Date(2024, 5, 10, 10, 8, 0, 0, time.Local)},
+		{"*/15 * * * *", time.Date(2024, 5, 10, 10, 15, 0, 0, time.Local)},
+		{"5/20 * * * *", time.Date(2024, 5, 10, 10, 25, 0, 0, time.Local)},
+		{"0 4 * * *", time.Date(2024, 5, 11, 4, 0, 0, 0, time.Local)},
+		{"30 6 * * 0", time.Date(2024, 5, 12, 6, 30, 0, 0, time.Local)},
+		{"30 6 * * 7", time.Date(2024, 5, 12, 6, 30, 0, 0, time.Local)},
+		// 日和周都受限时任一匹配即可
+		{"0 0 1 * 1", time.Date(2024, 5, 13, 0, 0, 0, 0, time.Local)},
+		{"0 0 1 6 *", time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)},
+	}
+	for _, c := range cases {
+		schedule, err := ParseCron(c.expr)
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		if next := schedule.Next(base); !next.Equal(c.expected) {
+			t.Errorf("%q: next = %v, expected %v", c.expr, next, c.expected)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package core
+
+import (
+	"math"
+	"os"
+
+	"github.com/candbright/go-server/pkg/downloader"
+)
+
+// DownloadProgress 版本压缩包的下载进度
+type DownloadProgress struct {
+	Version string `json:"version"`
+	// State running、done 或 failed
+	State      string  `json:"state"`
+	Downloaded int64   `json:"downloaded"`
+	Total      int64   `json:"total"`
+	Percent    float64 `json:"percent"`
+	// Speed 下载速度，单位字节每秒
+	Speed float64 `json:"speed"`
+	// ETA 预计剩余秒数，无法估算时为 0
+	ETA   int64  `json:"eta"`
+	Error string `json:"error,omitempty"`
+}
+
+// DownloadProgress 返回版本压缩包的下载进度，既没有下载器也没有压缩包时返回 false
+func (manager *ServerManager) DownloadProgress(version string) (DownloadProgress, bool) {
+	value, ok := manager.downloaders.Load(version)
+	if !ok {
+		// 已存在的压缩包（之前下载或从镜像复制）视为下载完成
+		info, err := os.Stat(manager.ZipFile(version))
+		if err != nil || !manager.ZipExist(version) {
+			return DownloadProgress{}, false
+		}
+		return DownloadProgress{
+			Version:    version,
+			State:      JobDone,
+			Downloaded: info.Size(),
+			Total:      info.Size(),
+			Percent:    100,
+		}, true
+	}
+	d := value.(*downloader.Downloader)
+	return newDownloadProgress(version, d.GetCurrentStatus(), d.Running()), true
+}
+
+func newDownloadProgress(version string, status downloader.DownloadStatus, running bool) DownloadProgress {
+	progress := DownloadProgress{
+		Version:    version,
+		State:      JobRunning,
+		Downloaded: status.Downloaded,
+		Total:      status.TotalBytes,
+		Percent:    status.Percentage,
+		Speed:      status.Speed * 1024,
+	}
+	switch {
+	case status.Err != nil:
+		progress.State = JobFailed
+		progress.Error = status.Err.Error()
+		progress.Speed = 0
+	case !running:
+		progress.State = JobDone
+		progress.Speed = 0
+	case progress.Total > 0 && progress.Speed > 0:
+		remaining := float64(progress.Total - progress.Downloaded)
+		progress.ETA = int64(math.Ceil(remaining / progress.Speed))
+	}
+	return progress
+}
@@ -0,0 +1,136 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/candbright/go-server/pkg/downloader"
+)
+
+func TestServerManager_DownloadProgress(t *testing.T) {
+	manager := testManager(t)
+	if err := os.MkdirAll(manager.VersionsDir(), os.ModePerm); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if _, ok := manager.DownloadProgress("1.21.62.01"); ok {
+		t.Fatal("unknown version should have no progress")
+	}
+
+	const size = 64 * 1024
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(size))
+		if r.Method == http.MethodHead {
+			return
+		}
+		_, _ = w.Write(make([]byte, size/2))
+		w.(http.Flusher).Flush()
+		<-release
+		_, _ = w.Write(make([]byte, size/2))
+	}))
+	defer srv.Close()
+
+	d := downloader.NewDownloader()
+	manager.downloaders.Store("1.21.62.01", d)
+	d.Download(srv.URL, manager.ZipFile("1.21.62.01"))
+	var progress DownloadProgress
+	waitFor(t, 5*time.Second, func() bool {
+		progress, _ = manager.DownloadProgress("1.21.62.01")
+		return progress.Downloaded == size/2
+	})
+	if progress.State != JobRunning || progress.Total != size || progress.Percent != 50 || progress.ETA <= 0 {
+		t.Fatalf("unexpected progress: %+v", progress)
+	}
+	close(release)
+	waitFor(t, 5*time.Second, func() bool {
+		progress, _ = manager.DownloadProgress("1.21.62.01")
+		return progress.State != JobRunning
+	})
+	if progress.State != JobDone || progress.Percent != 100 || progress.ETA != 0 {
+		t.Fatalf("unexpected progress: %+v", progress)
+	}
+
+	d = downloader.NewDownloader()
+	manager.downloaders.Store("1.21.50.10", d)
+	d.Download(srv.URL+"/missing", manager.ZipFile("1.21.50.10"))
+	waitFor(t, 5*time.Second, func() bool {
+		progress, _ = manager.DownloadProgress("1.21.50.10")
+		return progress.State != JobRunning
+	})
+	if progress.State != JobFailed || progress.Error == "" {
+		t.Fatalf("unexpected progress: %+v", progress)
+	}
+
+	// 没有下载器的完整压缩包视为已完成
+	writeTestZip(t, manager.ZipFile("1.21.44.01"), map[string]string{"bedrock_server": fakeServerScript})
+	progress, ok := manager.DownloadProgress("1.21.44.01")
+	if !ok || progress.State != JobDone || progress.Total == 0 {
+		t.Fatalf("unexpected progress: %+v", progress)
+	}
+}
+
+func TestServerManager_FetchVersion(t *testing.T) {
+	manager := testManager(t)
+	archive := path.Join(t.TempDir(), "server.zip")
+	writeTestZip(t, archive, map[string]string{"bedrock_server": fakeServerScript})
+	var fail atomic.Bool
+	fail.Store(true)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		http.ServeFile(w, r, archive)
+	}))
+	defer srv.Close()
+	manifest := path.Join(t.TempDir(), "versions.json")
+	err := os.WriteFile(manifest, []byte(`{"versions":[{"version":"1.21.62.01","url":"`+srv.URL+`"}]}`), 0666)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	manager.catalog = NewVersionCatalog(time.Hour, &ManifestVersionSource{File: manifest})
+
+	if err = manager.fetchVersion("1.21.62.01"); err == nil {
+		t.Fatal("failed download should return an error")
+	}
+	if Exists(manager.ZipFile("1.21.62.01")) {
+		t.Fatal("failed download should not leave an archive")
+	}
+	// 同一个下载器可以再次下载
+	fail.Store(false)
+	if err = manager.fetchVersion("1.21.62.01"); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if !manager.ZipExist("1.21.62.01") || !Exists(checksumFile(manager.ZipFile("1.21.62.01"))) {
+		t.Fatal("expected a verified archive with checksum")
+	}
+}
+
+func TestServerManager_DownloadVersionFromMirror(t *testing.T) {
+	manager := testManager(t)
+	mirror := t.TempDir()
+	writeTestZip(t, path.Join(mirror, manager.ZipFileName("1.21.62.01")), map[string]string{"bedrock_server": fakeServerScript})
+	manager.catalog = NewVersionCatalog(time.Hour, &MirrorVersionSource{Dir: mirror})
+
+	if err := manager.fetchVersion("1.21.62.01"); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	// 复制完成时记录校验和
+	sum, err := fileSHA256(manager.ZipFile("1.21.62.01"))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if stored := manager.storedChecksum("1.21.62.01"); stored != sum {
+		t.Fatalf("unexpected checksum: %s, want %s", stored, sum)
+	}
+}
@@ -0,0 +1,107 @@
+package core
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const followInterval = 200 * time.Millisecond
+
+// followFile 从 offset 处开始逐行读取 file 的新增内容并回调 emit。
+// 读到文件末尾时调用 stop，stop 返回 true 则输出剩余内容后结束；文件尚未创建时会等待最多 5 秒。
+// maxSize 大于 0 时，读到末尾且文件超过 maxSize 会轮转：内容复制到 file.1 后将 file 截断并从头读取，
+// 要求写入方以追加方式打开文件。文件被截断到当前读取位置之前时同样从头读取。
+func followFile(file string, offset int64, maxSize int64, stop func() bool, emit func(line string)) {
+	var f *os.File
+	var err error
+	for i := 0; i < 25; i++ {
+		f, err = os.Open(file)
+		if err == nil {
+			break
+		}
+		time.Sleep(followInterval)
+	}
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	if offset > 0 {
+		if _, err = f.Seek(offset, io.SeekStart); err != nil {
+			return
+		}
+	}
+
+	reader := bufio.NewReader(f)
+	partial := ""
+	pos := offset
+	for {
+		chunk, err := reader.ReadString('\n')
+		pos += int64(len(chunk))
+		if err == nil {
+			emit(partial + chunk)
+			partial = ""
+			continue
+		}
+		if err != io.EOF {
+			return
+		}
+		partial += chunk
+		info, statErr := os.Stat(file)
+		truncated := statErr == nil && info.Size() < pos
+		if statErr == nil && maxSize > 0 && pos >= maxSize && info.Size() == pos {
+			if truncated, err = rotateFile(file, pos); err != nil {
+				return
+			}
+		}
+		if truncated {
+			if _, err = f.Seek(0, io.SeekStart); err != nil {
+				return
+			}
+			reader.Reset(f)
+			pos = 0
+			continue
+		}
+		if stop() {
+			if partial != "" {
+				emit(partial)
+			}
+			return
+		}
+		time.Sleep(followInterval)
+	}
+}
+
+// rotateFile 将 file 的内容复制到 file.1 后截断 file。复制期间有新内容写入时不截断，返回 false 等待下次轮转；
+// 从检查大小到截断之间写入的内容会丢失
+func rotateFile(file string, size int64) (bool, error) {
+	src, err := os.Open(file)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	defer src.Close()
+	dst, err := os.Create(file + ".1")
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	defer dst.Close()
+	if _, err = io.CopyN(dst, src, size); err != nil {
+		return false, errors.WithStack(err)
+	}
+	if fileSize(file) != size {
+		return false, nil
+	}
+	return true, errors.WithStack(os.Truncate(file, 0))
+}
+
+// fileSize 返回文件当前大小，文件不存在时返回 0
+func fileSize(file string) int64 {
+	info, err := os.Stat(file)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
@@ -0,0 +1,67 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFollowFile_Rotate(t *testing.T) {
+	file := path.Join(t.TempDir(), consoleLogFile)
+	writer, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	defer writer.Close()
+
+	var mu sync.Mutex
+	lines := make([]string, 0)
+	stopped := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		followFile(file, 0, 64, func() bool {
+			select {
+			case <-stopped:
+				return true
+			default:
+				return false
+			}
+		}, func(line string) {
+			mu.Lock()
+			lines = append(lines, line)
+			mu.Unlock()
+		})
+		close(done)
+	}()
+
+	// 每次写入后等待读取完毕并留出轮转的时间，轮转期间写入的内容可能丢失
+	const total = 20
+	for i := 0; i < total; i++ {
+		if _, err = fmt.Fprintf(writer, "[INFO] line %02d\n", i); err != nil {
+			t.Fatalf("%+v", err)
+		}
+		waitFor(t, 5*time.Second, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(lines) == i+1
+		})
+		time.Sleep(followInterval / 4)
+	}
+	close(stopped)
+	<-done
+
+	for i, line := range lines {
+		if line != fmt.Sprintf("[INFO] line %02d\n", i) {
+			t.Fatalf("line %d: unexpected %q", i, line)
+		}
+	}
+	if size := fileSize(file); size >= 64+16 {
+		t.Fatalf("log should have been rotated, size %d", size)
+	}
+	if !Exists(file + ".1") {
+		t.Fatal("rotated log should be kept")
+	}
+}
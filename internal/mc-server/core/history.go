@@ -0,0 +1,270 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/candbright/go-log/log"
+	"github.com/pkg/errors"
+)
+
+// dayLayout 按天统计时使用的日期格式
+const dayLayout = "2006-01-02"
+
+// PlayerSession 玩家一次进出服务器的记录
+type PlayerSession struct {
+	ServerID string    `json:"server_id"`
+	Xuid     string    `json:"xuid"`
+	Name     string    `json:"name"`
+	Join     time.Time `json:"join"`
+	Leave    time.Time `json:"leave"`
+	// Duration 在线时长，单位秒
+	Duration int64 `json:"duration"`
+}
+
+// SessionFilter 查询玩家记录的条件，零值字段不参与过滤
+type SessionFilter struct {
+	ServerID string
+	Xuid     string
+	Name     string
+	// From、To 按进入时间过滤，[From, To)
+	From time.Time
+	To   time.Time
+}
+
+func (filter SessionFilter) match(session PlayerSession) bool {
+	if filter.ServerID != "" && session.ServerID != filter.ServerID {
+		return false
+	}
+	if filter.Xuid != "" && session.Xuid != filter.Xuid {
+		return false
+	}
+	if filter.Name != "" && session.Name != filter.Name {
+		return false
+	}
+	if !filter.From.IsZero() && session.Join.Before(filter.From) {
+		return false
+	}
+	if !filter.To.IsZero() && !session.Join.Before(filter.To) {
+		return false
+	}
+	return true
+}
+
+// PlaytimeEntry 玩家在线时长排行中的一项
+type PlaytimeEntry struct {
+	Xuid     string `json:"xuid"`
+	Name     string `json:"name"`
+	Sessions int    `json:"sessions"`
+	// Playtime 累计在线时长，单位秒
+	Playtime int64     `json:"playtime"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// DailyPlayers 某一天进入过服务器的玩家
+type DailyPlayers struct {
+	Date    string   `json:"date"`
+	Count   int      `json:"count"`
+	Players []string `json:"players"`
+}
+
+// PlayerHistory 持久化所有服务器的玩家进出记录。
+// 记录以 JSON Lines 格式追加写入，避免记录增多后每次都重写整个文件
+type PlayerHistory struct {
+	file     string
+	mu       sync.Mutex
+	sessions []PlayerSession
+	// open 尚未离开的玩家，key: 服务器 id + 玩家名。
+	// 同时保存到 openFile，面板重启并重新接管进程后，玩家离开时仍能找到对应的进入记录
+	open map[string]PlayerEvent
+}
+
+func NewPlayerHistory(file string) (*PlayerHistory, error) {
+	history := &PlayerHistory{
+		file:     file,
+		sessions: make([]PlayerSession, 0),
+		open:     make(map[string]PlayerEvent),
+	}
+	if data, err := os.ReadFile(history.openFile()); err == nil {
+		if err = json.Unmarshal(data, &history.open); err != nil {
+			log.WithError(err).Warn("skip invalid open player sessions")
+			history.open = make(map[string]PlayerEvent)
+		}
+	}
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return history, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var session PlayerSession
+		if err = json.Unmarshal(scanner.Bytes(), &session); err != nil {
+			// 跳过写入中断等原因产生的损坏行
+			log.WithError(err).Warn("skip invalid player session record")
+			continue
+		}
+		history.sessions = append(history.sessions, session)
+	}
+	return history, errors.WithStack(scanner.Err())
+}
+
+// Record 处理一次玩家进出事件，玩家离开时写入完整的会话记录
+func (h *PlayerHistory) Record(serverID string, event PlayerEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	key := serverID + "/" + event.Name
+	if event.Type == PlayerJoin {
+		h.open[key] = event
+		h.saveOpen()
+		return
+	}
+	join, ok := h.open[key]
+	if !ok {
+		return
+	}
+	delete(h.open, key)
+	h.saveOpen()
+	session := PlayerSession{
+		ServerID: serverID,
+		Xuid:     join.Xuid,
+		Name:     join.Name,
+		Join:     join.Time,
+		Leave:    event.Time,
+		Duration: int64(event.Time.Sub(join.Time).Seconds()),
+	}
+	if session.Xuid == "" {
+		session.Xuid = event.Xuid
+	}
+	if err := h.append(session); err != nil {
+		log.WithError(err).Error("save player session failed")
+	}
+	h.sessions = append(h.sessions, session)
+}
+
+// openFile 保存尚未离开的玩家的文件，与记录文件位于同一目录
+func (h *PlayerHistory) openFile() string {
+	return path.Join(path.Dir(h.file), "open_sessions.json")
+}
+
+func (h *PlayerHistory) saveOpen() {
+	data, err := json.Marshal(h.open)
+	if err == nil {
+		err = os.MkdirAll(path.Dir(h.file), os.ModePerm)
+	}
+	if err == nil {
+		err = os.WriteFile(h.openFile(), data, 0666)
+	}
+	if err != nil {
+		log.WithError(err).Error("save open player sessions failed")
+	}
+}
+
+func (h *PlayerHistory) append(session PlayerSession) error {
+	if err := os.MkdirAll(path.Dir(h.file), os.ModePerm); err != nil {
+		return errors.WithStack(err)
+	}
+	f, err := os.OpenFile(h.file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer f.Close()
+	data, err := json.Marshal(session)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	_, err = f.Write(append(data, '\n'))
+	return errors.WithStack(err)
+}
+
+// Sessions 返回符合条件的记录，按进入时间倒序，limit <= 0 时返回全部
+func (h *PlayerHistory) Sessions(filter SessionFilter, limit int) []PlayerSession {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	result := make([]PlayerSession, 0)
+	for i := len(h.sessions) - 1; i >= 0; i-- {
+		if filter.match(h.sessions[i]) {
+			result = append(result, h.sessions[i])
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Join.After(result[j].Join)
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+// Leaderboard 按累计在线时长降序统计玩家，limit <= 0 时返回全部
+func (h *PlayerHistory) Leaderboard(filter SessionFilter, limit int) []PlaytimeEntry {
+	entries := make(map[string]*PlaytimeEntry)
+	for _, session := range h.Sessions(filter, 0) {
+		key := playerKey(session)
+		entry, ok := entries[key]
+		if !ok {
+			// 记录按时间倒序，第一次遇到时即为最近一次使用的名字
+			entry = &PlaytimeEntry{Xuid: session.Xuid, Name: session.Name, LastSeen: session.Leave}
+			entries[key] = entry
+		}
+		entry.Sessions++
+		entry.Playtime += session.Duration
+	}
+	result := make([]PlaytimeEntry, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, *entry)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Playtime == result[j].Playtime {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].Playtime > result[j].Playtime
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+// DailyUniquePlayers 按天统计进入过服务器的不同玩家，日期按服务器本地时区划分，按日期升序
+func (h *PlayerHistory) DailyUniquePlayers(filter SessionFilter) []DailyPlayers {
+	days := make(map[string]map[string]string) // date -> player key -> name
+	for _, session := range h.Sessions(filter, 0) {
+		date := session.Join.Local().Format(dayLayout)
+		if days[date] == nil {
+			days[date] = make(map[string]string)
+		}
+		if _, ok := days[date][playerKey(session)]; !ok {
+			days[date][playerKey(session)] = session.Name
+		}
+	}
+	result := make([]DailyPlayers, 0, len(days))
+	for date, players := range days {
+		daily := DailyPlayers{Date: date, Count: len(players), Players: make([]string, 0, len(players))}
+		for _, name := range players {
+			daily.Players = append(daily.Players, name)
+		}
+		sort.Strings(daily.Players)
+		result = append(result, daily)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Date < result[j].Date
+	})
+	return result
+}
+
+// playerKey 优先以 xuid 识别玩家，离线模式下没有 xuid 时使用玩家名
+func playerKey(session PlayerSession) string {
+	if session.Xuid != "" {
+		return session.Xuid
+	}
+	return "name:" + session.Name
+}
@@ -0,0 +1,129 @@
+package core
+
+import (
+	"fmt"
+	"path"
+	"testing"
+	"time"
+)
+
+func recordSession(h *PlayerHistory, serverID, name, xuid string, join time.Time, duration time.Duration) {
+	h.Record(serverID, PlayerEvent{Type: PlayerJoin, Name: name, Xuid: xuid, Time: join})
+	h.Record(serverID, PlayerEvent{Type: PlayerLeave, Name: name, Xuid: xuid, Time: join.Add(duration)})
+}
+
+func TestPlayerHistory(t *testing.T) {
+	file := path.Join(t.TempDir(), "players", "sessions.jsonl")
+	history, err := NewPlayerHistory(file)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	day := time.Date(2025, 1, 1, 10, 0, 0, 0, time.Local)
+	recordSession(history, "1", "Steve", "100", day, time.Hour)
+	recordSession(history, "1", "Alex", "200", day.Add(time.Hour), 30*time.Minute)
+	recordSession(history, "1", "Steve", "100", day.Add(24*time.Hour), 2*time.Hour)
+	recordSession(history, "2", "Steve", "100", day, 5*time.Hour)
+	// 没有对应进入记录的离开事件不产生记录
+	history.Record("1", PlayerEvent{Type: PlayerLeave, Name: "Ghost", Time: day})
+
+	// 重新加载后记录仍然存在
+	history, err = NewPlayerHistory(file)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	sessions := history.Sessions(SessionFilter{ServerID: "1", Xuid: "100"}, 0)
+	if len(sessions) != 2 || sessions[0].Duration != 7200 || !sessions[0].Join.After(sessions[1].Join) {
+		t.Fatalf("unexpected sessions: %+v", sessions)
+	}
+	if sessions = history.Sessions(SessionFilter{Xuid: "100"}, 0); len(sessions) != 3 {
+		t.Fatalf("expected 3 sessions across servers, got %d", len(sessions))
+	}
+	if sessions = history.Sessions(SessionFilter{}, 1); len(sessions) != 1 {
+		t.Fatalf("limit not applied: %d", len(sessions))
+	}
+
+	board := history.Leaderboard(SessionFilter{ServerID: "1"}, 0)
+	if len(board) != 2 || board[0].Name != "Steve" || board[0].Playtime != 3*3600 || board[0].Sessions != 2 {
+		t.Fatalf("unexpected leaderboard: %+v", board)
+	}
+	if board[1].Name != "Alex" || board[1].Playtime != 1800 {
+		t.Fatalf("unexpected leaderboard: %+v", board)
+	}
+
+	daily := history.DailyUniquePlayers(SessionFilter{ServerID: "1"})
+	if len(daily) != 2 {
+		t.Fatalf("expected 2 days, got %+v", daily)
+	}
+	if daily[0].Date != "2025-01-01" || daily[0].Count != 2 || fmt.Sprint(daily[0].Players) != "[Alex Steve]" {
+		t.Fatalf("unexpected first day: %+v", daily[0])
+	}
+	if daily[1].Count != 1 {
+		t.Fatalf("unexpected second day: %+v", daily[1])
+	}
+	daily = history.DailyUniquePlayers(SessionFilter{ServerID: "1", From: day.Add(12 * time.Hour)})
+	if len(daily) != 1 || daily[0].Date != "2025-01-02" {
+		t.Fatalf("time filter not applied: %+v", daily)
+	}
+}
+
+func TestPlayerHistory_OpenSessionSurvivesReload(t *testing.T) {
+	file := path.Join(t.TempDir(), "players", "sessions.jsonl")
+	history, err := NewPlayerHistory(file)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	join := time.Date(2025, 1, 1, 10, 0, 0, 0, time.Local)
+	history.Record("1", PlayerEvent{Type: PlayerJoin, Name: "Steve", Xuid: "100", Time: join})
+
+	// 面板重启后，玩家离开时仍能与重启前的进入记录配对
+	history, err = NewPlayerHistory(file)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	history.Record("1", PlayerEvent{Type: PlayerLeave, Name: "Steve", Xuid: "100", Time: join.Add(time.Hour)})
+	sessions := history.Sessions(SessionFilter{}, 0)
+	if len(sessions) != 1 || sessions[0].Duration != 3600 || !sessions[0].Join.Equal(join) {
+		t.Fatalf("unexpected sessions: %+v", sessions)
+	}
+
+	history, err = NewPlayerHistory(file)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if len(history.open) != 0 {
+		t.Fatalf("closed session should not stay open: %+v", history.open)
+	}
+}
+
+func TestServer_PlayerHistory(t *testing.T) {
+	server := testFakeServer(t)
+	history, err := NewPlayerHistory(path.Join(t.TempDir(), "sessions.jsonl"))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	server.players.OnEvent(func(event PlayerEvent) {
+		history.Record(server.id, event)
+	})
+	err = server.Start()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	_, err = server.process.WaitReady(5 * time.Second)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	_ = server.process.ExecCmd("join", "Steve", "100")
+	_ = server.process.ExecCmd("leave", "Steve", "100")
+	// 服务器关闭时仍在线的玩家也会生成记录
+	_ = server.process.ExecCmd("join", "Alex", "200")
+	waitFor(t, 5*time.Second, func() bool {
+		return len(server.OnlinePlayers()) == 1
+	})
+	err = server.Stop()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	waitFor(t, 5*time.Second, func() bool {
+		return len(history.Sessions(SessionFilter{ServerID: server.id}, 0)) == 2
+	})
+}
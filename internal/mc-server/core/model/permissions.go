@@ -0,0 +1,23 @@
+package model
+
+const (
+	PermissionOperator = "operator"
+	PermissionMember   = "member"
+	PermissionVisitor  = "visitor"
+)
+
+type Permissions []PermissionEntry
+
+type PermissionEntry struct {
+	Permission string `json:"permission"`
+	XUid       string `json:"xuid"`
+}
+
+// ValidPermission 是否为 bedrock 支持的权限等级
+func ValidPermission(permission string) bool {
+	switch permission {
+	case PermissionOperator, PermissionMember, PermissionVisitor:
+		return true
+	}
+	return false
+}
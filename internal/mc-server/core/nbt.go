@@ -0,0 +1,201 @@
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// NBT 标签类型，bedrock 的 level.dat 使用小端序
+const (
+	nbtEnd byte = iota
+	nbtByte
+	nbtShort
+	nbtInt
+	nbtLong
+	nbtFloat
+	nbtDouble
+	nbtByteArray
+	nbtString
+	nbtList
+	nbtCompound
+	nbtIntArray
+	nbtLongArray
+)
+
+// nbtMaxDepth 嵌套层数上限，防止损坏的文件导致无限递归
+const nbtMaxDepth = 512
+
+// levelDatHeaderSize level.dat 开头的存储版本和数据长度，各占 4 字节
+const levelDatHeaderSize = 8
+
+// ReadLastOpenedVersion 读取世界 level.dat 中的 lastOpenedWithVersion，即最近一次打开该世界的游戏版本
+func ReadLastOpenedVersion(levelDat string) ([]int, error) {
+	data, err := os.ReadFile(levelDat)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(data) < levelDatHeaderSize {
+		return nil, errors.Errorf("%s: file too short", levelDat)
+	}
+	r := &nbtReader{r: bytes.NewReader(data[levelDatHeaderSize:])}
+	tag, err := r.byte()
+	if err != nil {
+		return nil, errors.Wrap(err, levelDat)
+	}
+	if tag != nbtCompound {
+		return nil, errors.Errorf("%s: root tag is not a compound", levelDat)
+	}
+	if _, err = r.string(); err != nil {
+		return nil, errors.Wrap(err, levelDat)
+	}
+	for {
+		tag, err = r.byte()
+		if err != nil {
+			return nil, errors.Wrap(err, levelDat)
+		}
+		if tag == nbtEnd {
+			return nil, errors.Errorf("%s: lastOpenedWithVersion not found", levelDat)
+		}
+		name, err := r.string()
+		if err != nil {
+			return nil, errors.Wrap(err, levelDat)
+		}
+		if name == "lastOpenedWithVersion" && tag == nbtList {
+			version, err := r.intList()
+			return version, errors.Wrap(err, levelDat)
+		}
+		if err = r.skip(tag, 0); err != nil {
+			return nil, errors.Wrap(err, levelDat)
+		}
+	}
+}
+
+// nbtReader 只支持读取定位 level.dat 字段所需的最少操作
+type nbtReader struct {
+	r *bytes.Reader
+}
+
+func (n *nbtReader) byte() (byte, error) {
+	b, err := n.r.ReadByte()
+	return b, errors.WithStack(err)
+}
+
+func (n *nbtReader) int32() (int32, error) {
+	var v int32
+	err := binary.Read(n.r, binary.LittleEndian, &v)
+	return v, errors.WithStack(err)
+}
+
+func (n *nbtReader) string() (string, error) {
+	var length uint16
+	if err := binary.Read(n.r, binary.LittleEndian, &length); err != nil {
+		return "", errors.WithStack(err)
+	}
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(n.r, buf); err != nil {
+		return "", errors.WithStack(err)
+	}
+	return string(buf), nil
+}
+
+func (n *nbtReader) intList() ([]int, error) {
+	tag, err := n.byte()
+	if err != nil {
+		return nil, err
+	}
+	length, err := n.int32()
+	if err != nil {
+		return nil, err
+	}
+	if tag != nbtInt && length > 0 {
+		return nil, errors.Errorf("expected a list of int, got tag type %d", tag)
+	}
+	if length < 0 || int64(length)*4 > int64(n.r.Len()) {
+		return nil, errors.Errorf("invalid list length %d", length)
+	}
+	values := make([]int, 0, length)
+	for i := int32(0); i < length; i++ {
+		v, err := n.int32()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, int(v))
+	}
+	return values, nil
+}
+
+// discard 跳过 count 个 size 字节的元素
+func (n *nbtReader) discard(count int64, size int64) error {
+	if count < 0 || count*size > int64(n.r.Len()) {
+		return errors.Errorf("invalid length %d", count)
+	}
+	_, err := n.r.Seek(count*size, io.SeekCurrent)
+	return errors.WithStack(err)
+}
+
+// skip 跳过一个 tag 类型标签的负载
+func (n *nbtReader) skip(tag byte, depth int) error {
+	if depth > nbtMaxDepth {
+		return errors.New("nbt nested too deep")
+	}
+	switch tag {
+	case nbtByte:
+		return n.discard(1, 1)
+	case nbtShort:
+		return n.discard(1, 2)
+	case nbtInt, nbtFloat:
+		return n.discard(1, 4)
+	case nbtLong, nbtDouble:
+		return n.discard(1, 8)
+	case nbtString:
+		_, err := n.string()
+		return err
+	case nbtByteArray, nbtIntArray, nbtLongArray:
+		length, err := n.int32()
+		if err != nil {
+			return err
+		}
+		size := map[byte]int64{nbtByteArray: 1, nbtIntArray: 4, nbtLongArray: 8}[tag]
+		return n.discard(int64(length), size)
+	case nbtList:
+		elem, err := n.byte()
+		if err != nil {
+			return err
+		}
+		length, err := n.int32()
+		if err != nil {
+			return err
+		}
+		if length < 0 {
+			return errors.Errorf("invalid list length %d", length)
+		}
+		for i := int32(0); i < length; i++ {
+			if err = n.skip(elem, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	case nbtCompound:
+		for {
+			child, err := n.byte()
+			if err != nil {
+				return err
+			}
+			if child == nbtEnd {
+				return nil
+			}
+			if _, err = n.string(); err != nil {
+				return err
+			}
+			if err = n.skip(child, depth+1); err != nil {
+				return err
+			}
+		}
+	default:
+		return errors.Errorf("unknown nbt tag type %d", tag)
+	}
+}
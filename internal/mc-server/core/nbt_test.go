@@ -0,0 +1,111 @@
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path"
+	"testing"
+)
+
+// nbtTestWriter 按小端序写入测试用的 NBT 数据
+type nbtTestWriter struct {
+	bytes.Buffer
+}
+
+func (w *nbtTestWriter) tag(tag byte, name string) {
+	w.WriteByte(tag)
+	w.str(name)
+}
+
+func (w *nbtTestWriter) str(s string) {
+	_ = binary.Write(w, binary.LittleEndian, uint16(len(s)))
+	w.WriteString(s)
+}
+
+func (w *nbtTestWriter) int32(v int32) {
+	_ = binary.Write(w, binary.LittleEndian, v)
+}
+
+// writeLevelDat 写入包含 lastOpenedWithVersion 和若干其他字段的 level.dat
+func writeLevelDat(t *testing.T, file string, version []int) {
+	var body nbtTestWriter
+	body.tag(nbtCompound, "")
+	body.tag(nbtString, "LevelName")
+	body.str("Bedrock level")
+	body.tag(nbtLong, "RandomSeed")
+	body.Write(make([]byte, 8))
+	body.tag(nbtCompound, "abilities")
+	body.tag(nbtFloat, "flySpeed")
+	body.Write(make([]byte, 4))
+	body.tag(nbtList, "tags")
+	body.WriteByte(nbtString)
+	body.int32(2)
+	body.str("a")
+	body.str("b")
+	body.WriteByte(nbtEnd)
+	body.tag(nbtByteArray, "data")
+	body.int32(3)
+	body.Write([]byte{1, 2, 3})
+	body.tag(nbtList, "lastOpenedWithVersion")
+	body.WriteByte(nbtInt)
+	body.int32(int32(len(version)))
+	for _, v := range version {
+		body.int32(int32(v))
+	}
+	body.tag(nbtInt, "StorageVersion")
+	body.int32(10)
+	body.WriteByte(nbtEnd)
+
+	var file0 nbtTestWriter
+	file0.int32(10)
+	file0.int32(int32(body.Len()))
+	file0.Write(body.Bytes())
+	if err := os.MkdirAll(path.Dir(file), os.ModePerm); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err := os.WriteFile(file, file0.Bytes(), 0666); err != nil {
+		t.Fatalf("%+v", err)
+	}
+}
+
+func TestReadLastOpenedVersion(t *testing.T) {
+	file := path.Join(t.TempDir(), "level.dat")
+	writeLevelDat(t, file, []int{1, 21, 44, 1, 0})
+	version, err := ReadLastOpenedVersion(file)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if formatVersion(version) != "1.21.44.1.0" {
+		t.Fatalf("unexpected version: %v", version)
+	}
+
+	data, _ := os.ReadFile(file)
+	if err = os.WriteFile(file, data[:len(data)/2], 0666); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if _, err = ReadLastOpenedVersion(file); err == nil {
+		t.Fatal("truncated level.dat should fail")
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	target, _ := parseVersion("1.21.44.01")
+	tests := []struct {
+		opened []int
+		want   int
+	}{
+		{opened: []int{1, 21, 44, 1, 0}, want: 0},
+		{opened: []int{1, 21, 50, 7, 0}, want: 1},
+		{opened: []int{1, 20, 80, 5, 0}, want: -1},
+		{opened: []int{1, 21, 44, 1, 1}, want: 0},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.opened, target); got != tt.want {
+			t.Errorf("compareVersions(%v) = %d, want %d", tt.opened, got, tt.want)
+		}
+	}
+	if _, ok := parseVersion("fake"); ok {
+		t.Error("non numeric version should not parse")
+	}
+}
@@ -3,8 +3,11 @@ package core
 import (
 	"fmt"
 	"github.com/candbright/go-log/log"
+	"github.com/pkg/errors"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
 )
 
@@ -96,3 +99,82 @@ func Unzip(src, dst string) error {
 		return fmt.Errorf("unsupported platform")
 	}
 }
+
+// dirSize 统计目录下所有普通文件的大小，skip 返回 true 的相对路径不计入
+func dirSize(dir string, skip func(rel string, info os.FileInfo) bool) (int64, error) {
+	var total int64
+	err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, file)
+		if skip != nil && skip(rel, info) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Mode().IsRegular() {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total, errors.WithStack(err)
+}
+
+// copyDir 将 src 目录递归复制到 dst，保留文件权限；只复制目录和普通文件，
+// skip 返回 true 的相对路径会被跳过，每写入一段数据回调 progress
+func copyDir(src, dst string, skip func(rel string, info os.FileInfo) bool, progress func(n int64)) error {
+	return filepath.Walk(src, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		rel, _ := filepath.Rel(src, file)
+		if skip != nil && skip(rel, info) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return errors.WithStack(os.MkdirAll(target, info.Mode().Perm()|0700))
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		return copyFile(file, target, info.Mode().Perm(), progress)
+	})
+}
+
+func copyFile(src, dst string, perm os.FileMode, progress func(n int64)) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	var w io.Writer = out
+	if progress != nil {
+		w = progressWriter{w: out, progress: progress}
+	}
+	if _, err = io.Copy(w, in); err != nil {
+		_ = out.Close()
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(out.Close())
+}
+
+type progressWriter struct {
+	w        io.Writer
+	progress func(n int64)
+}
+
+func (pw progressWriter) Write(p []byte) (int, error) {
+	n, err := pw.w.Write(p)
+	pw.progress(int64(n))
+	return n, err
+}
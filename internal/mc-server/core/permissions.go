@@ -0,0 +1,149 @@
+package core
+
+import (
+	"path"
+	"strings"
+
+	"github.com/candbright/go-server/internal/mc-server/core/model"
+	"github.com/candbright/go-server/pkg/dw"
+	"github.com/pkg/errors"
+)
+
+// PermissionChange 修改玩家权限等级的请求，xuid 与玩家名至少提供一项
+type PermissionChange struct {
+	Xuid       string `json:"xuid"`
+	Name       string `json:"name"`
+	Permission string `json:"permission"`
+}
+
+func (server *Server) PermissionsFilePath() string {
+	return path.Join(server.WorkDir(), "permissions.json")
+}
+
+func (server *Server) GetPermissions() (model.Permissions, error) {
+	if !server.ServerExist() {
+		return nil, errors.New("server not exist")
+	}
+	w, err := dw.JsonOrDefault[model.Permissions](server.PermissionsFilePath(), model.Permissions{})
+	if err != nil {
+		return nil, err
+	}
+	return w.Data, nil
+}
+
+// SetPermission 设置玩家的权限等级。能解析出 xuid 时总是写入 permissions.json；
+// 服务器运行时还会通过 op/deop 命令实时生效，此时只能设置 operator 或 member，且需要玩家名。
+func (server *Server) SetPermission(change PermissionChange) error {
+	if !model.ValidPermission(change.Permission) {
+		return errors.Errorf("unsupported permission level [%s]", change.Permission)
+	}
+	server.resolvePlayer(&change.Name, &change.Xuid)
+	if server.Active() {
+		if change.Name == "" {
+			return errors.New("player name is required while server is running")
+		}
+		var err error
+		switch change.Permission {
+		case model.PermissionOperator:
+			err = server.execCmd("op", quoteTarget(change.Name))
+		case model.PermissionMember:
+			err = server.execCmd("deop", quoteTarget(change.Name))
+		default:
+			// visitor 没有对应的命令，运行中的服务器也不会重新读取 permissions.json
+			err = errors.Errorf("permission level [%s] can only be set while server is stopped", change.Permission)
+		}
+		if err != nil || change.Xuid == "" {
+			return err
+		}
+	} else if change.Xuid == "" {
+		return errors.New("player xuid is required while server is stopped")
+	}
+	return server.editPermissions(func(permissions model.Permissions) (model.Permissions, error) {
+		for i := range permissions {
+			if permissions[i].XUid == change.Xuid {
+				permissions[i].Permission = change.Permission
+				return permissions, nil
+			}
+		}
+		return append(permissions, model.PermissionEntry{
+			Permission: change.Permission,
+			XUid:       change.Xuid,
+		}), nil
+	})
+}
+
+// DeletePermission 移除玩家的权限设置，玩家恢复为 server.properties 中的默认权限。
+// 能解析出 xuid 时总是从 permissions.json 中移除；服务器运行时还会通过 deop 命令实时生效。
+func (server *Server) DeletePermission(name, xuid string) error {
+	server.resolvePlayer(&name, &xuid)
+	active := server.Active()
+	if active {
+		if name == "" {
+			return errors.New("player name is required while server is running")
+		}
+		if err := server.execCmd("deop", quoteTarget(name)); err != nil || xuid == "" {
+			return err
+		}
+	} else if xuid == "" {
+		return errors.New("player xuid is required while server is stopped")
+	}
+	return server.editPermissions(func(permissions model.Permissions) (model.Permissions, error) {
+		result := make(model.Permissions, 0, len(permissions))
+		for _, entry := range permissions {
+			if entry.XUid != xuid {
+				result = append(result, entry)
+			}
+		}
+		// 运行中已通过 deop 生效，文件中没有记录不算失败
+		if len(result) == len(permissions) && !active {
+			return nil, errors.Errorf("player [%s] has no permission entry", xuid)
+		}
+		return result, nil
+	})
+}
+
+func (server *Server) editPermissions(edit func(permissions model.Permissions) (model.Permissions, error)) error {
+	if !server.ServerExist() {
+		return errors.New("server not exist")
+	}
+	w, err := dw.JsonOrDefault[model.Permissions](server.PermissionsFilePath(), model.Permissions{})
+	if err != nil {
+		return err
+	}
+	permissions, err := edit(w.Data)
+	if err != nil {
+		return err
+	}
+	w.Data = permissions
+	return w.Write()
+}
+
+// resolvePlayer 根据在线玩家和白名单补全缺少的玩家名或 xuid
+func (server *Server) resolvePlayer(name, xuid *string) {
+	if *name != "" && *xuid != "" {
+		return
+	}
+	fill := func(playerName, playerXuid string) bool {
+		if (*xuid != "" && playerXuid == *xuid) || (*name != "" && strings.EqualFold(playerName, *name)) {
+			if *name == "" {
+				*name = playerName
+			}
+			if *xuid == "" {
+				*xuid = playerXuid
+			}
+			return true
+		}
+		return false
+	}
+	for _, player := range server.OnlinePlayers() {
+		if fill(player.Name, player.Xuid) {
+			return
+		}
+	}
+	allowList, _ := server.GetAllowList()
+	for _, user := range allowList {
+		if fill(user.Name, user.XUid) {
+			return
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package core
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/candbright/go-server/internal/mc-server/core/model"
+)
+
+func TestServer_PermissionsStopped(t *testing.T) {
+	server := testFakeServer(t)
+	permissions, err := server.GetPermissions()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if len(permissions) != 0 {
+		t.Fatalf("expected no permissions, got %+v", permissions)
+	}
+	err = os.WriteFile(server.AllowListFilePath(),
+		[]byte(`[{"name":"Steve","xuid":"100","ignoresPlayerLimit":false}]`), 0666)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if err = server.SetPermission(PermissionChange{Xuid: "100", Permission: "admin"}); err == nil {
+		t.Fatal("invalid permission level should be rejected")
+	}
+	if err = server.SetPermission(PermissionChange{Name: "Nobody", Permission: model.PermissionOperator}); err == nil {
+		t.Fatal("unknown player without xuid should be rejected")
+	}
+	// 玩家名通过白名单解析为 xuid
+	err = server.SetPermission(PermissionChange{Name: "steve", Permission: model.PermissionOperator})
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	err = server.SetPermission(PermissionChange{Xuid: "200", Permission: model.PermissionVisitor})
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	err = server.SetPermission(PermissionChange{Xuid: "100", Permission: model.PermissionMember})
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	permissions, err = server.GetPermissions()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	expected := model.Permissions{
+		{Permission: model.PermissionMember, XUid: "100"},
+		{Permission: model.PermissionVisitor, XUid: "200"},
+	}
+	if len(permissions) != len(expected) || permissions[0] != expected[0] || permissions[1] != expected[1] {
+		t.Fatalf("unexpected permissions: %+v", permissions)
+	}
+
+	err = server.DeletePermission("", "200")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err = server.DeletePermission("", "200"); err == nil {
+		t.Fatal("deleting a missing entry should fail")
+	}
+	permissions, _ = server.GetPermissions()
+	if len(permissions) != 1 {
+		t.Fatalf("unexpected permissions after delete: %+v", permissions)
+	}
+}
+
+func TestServer_PermissionsRunning(t *testing.T) {
+	server := testFakeServer(t)
+	err := server.Start()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	_, err = server.process.WaitReady(5 * time.Second)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	_ = server.process.ExecCmd("join", "Steve", "100")
+	waitFor(t, 5*time.Second, func() bool {
+		return len(server.OnlinePlayers()) == 1
+	})
+	consoleHas := func(text string) bool {
+		for _, line := range server.Console().Lines(0) {
+			if line.Text == text {
+				return true
+			}
+		}
+		return false
+	}
+
+	// 运行中只提供 xuid 时从在线玩家解析玩家名
+	err = server.SetPermission(PermissionChange{Xuid: "100", Permission: model.PermissionOperator})
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if !consoleHas("[INFO] op Steve") {
+		t.Fatal("expected op command to be sent")
+	}
+	// 实时生效的同时写入 permissions.json，重启后仍然保留
+	permissions, err := server.GetPermissions()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if len(permissions) != 1 || permissions[0].XUid != "100" || permissions[0].Permission != model.PermissionOperator {
+		t.Fatalf("unexpected permissions while running: %+v", permissions)
+	}
+	err = server.SetPermission(PermissionChange{Name: "Big Alex", Permission: model.PermissionMember})
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if !consoleHas(`[INFO] deop "Big Alex"`) {
+		t.Fatal("expected quoted deop command to be sent")
+	}
+	if err = server.SetPermission(PermissionChange{Name: "Steve", Permission: model.PermissionVisitor}); err == nil {
+		t.Fatal("visitor level should not be settable while running")
+	}
+	if err = server.DeletePermission("", "999"); err == nil {
+		t.Fatal("unknown xuid should be rejected while running")
+	}
+	if err = server.DeletePermission("Steve", ""); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if permissions, _ = server.GetPermissions(); len(permissions) != 0 {
+		t.Fatalf("permission entry should be removed while running: %+v", permissions)
+	}
+}
@@ -0,0 +1,132 @@
+package core
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	PlayerJoin  = "join"
+	PlayerLeave = "leave"
+)
+
+var (
+	// playerConnectedRegex 匹配 "Player connected: Steve, xuid: 2535412345678901"
+	playerConnectedRegex = regexp.MustCompile(`Player connected: (.+?), xuid: ?(\d*)`)
+	// playerDisconnectedRegex 匹配 "Player disconnected: Steve, xuid: 2535412345678901, pfid: ..."
+	playerDisconnectedRegex = regexp.MustCompile(`Player disconnected: (.+?), xuid: ?(\d*)`)
+)
+
+type OnlinePlayer struct {
+	Name     string    `json:"name"`
+	Xuid     string    `json:"xuid"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// PlayerEvent 从服务器日志中解析出的一次玩家进出事件
+type PlayerEvent struct {
+	Type string    `json:"type"`
+	Name string    `json:"name"`
+	Xuid string    `json:"xuid"`
+	Time time.Time `json:"time"`
+}
+
+// parsePlayerEvent 解析玩家进出服务器的日志行
+func parsePlayerEvent(line ConsoleLine) (PlayerEvent, bool) {
+	event := PlayerEvent{Time: line.Time}
+	if match := playerConnectedRegex.FindStringSubmatch(line.Text); match != nil {
+		event.Type = PlayerJoin
+		event.Name, event.Xuid = strings.TrimSpace(match[1]), match[2]
+		return event, true
+	}
+	if match := playerDisconnectedRegex.FindStringSubmatch(line.Text); match != nil {
+		event.Type = PlayerLeave
+		event.Name, event.Xuid = strings.TrimSpace(match[1]), match[2]
+		return event, true
+	}
+	return event, false
+}
+
+// PlayerTracker 根据服务器日志维护在线玩家列表
+type PlayerTracker struct {
+	mu       sync.Mutex
+	online   map[string]OnlinePlayer // key: 玩家名
+	handlers []func(event PlayerEvent)
+}
+
+func NewPlayerTracker() *PlayerTracker {
+	return &PlayerTracker{
+		online: make(map[string]OnlinePlayer),
+	}
+}
+
+// OnEvent 注册玩家进出事件的处理函数
+func (t *PlayerTracker) OnEvent(handler func(event PlayerEvent)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.handlers = append(t.handlers, handler)
+}
+
+// Observe 处理一行控制台输出，识别到玩家进出时更新在线列表
+func (t *PlayerTracker) Observe(line ConsoleLine) {
+	event, ok := parsePlayerEvent(line)
+	if !ok {
+		return
+	}
+	t.mu.Lock()
+	switch event.Type {
+	case PlayerJoin:
+		t.online[event.Name] = OnlinePlayer{
+			Name:     event.Name,
+			Xuid:     event.Xuid,
+			JoinedAt: event.Time,
+		}
+	case PlayerLeave:
+		delete(t.online, event.Name)
+	}
+	handlers := t.handlers
+	t.mu.Unlock()
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
+
+// Online 返回在线玩家，按进入时间排序
+func (t *PlayerTracker) Online() []OnlinePlayer {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	players := make([]OnlinePlayer, 0, len(t.online))
+	for _, player := range t.online {
+		players = append(players, player)
+	}
+	sort.Slice(players, func(i, j int) bool {
+		if players[i].JoinedAt.Equal(players[j].JoinedAt) {
+			return players[i].Name < players[j].Name
+		}
+		return players[i].JoinedAt.Before(players[j].JoinedAt)
+	})
+	return players
+}
+
+// Reset 服务器进程退出后清空在线列表，仍在线的玩家视为离开
+func (t *PlayerTracker) Reset(at time.Time) {
+	t.mu.Lock()
+	online := t.online
+	t.online = make(map[string]OnlinePlayer)
+	handlers := t.handlers
+	t.mu.Unlock()
+	for _, player := range online {
+		event := PlayerEvent{
+			Type: PlayerLeave,
+			Name: player.Name,
+			Xuid: player.Xuid,
+			Time: at,
+		}
+		for _, handler := range handlers {
+			handler(event)
+		}
+	}
+}
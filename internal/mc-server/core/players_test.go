@@ -0,0 +1,95 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePlayerEvent(t *testing.T) {
+	tests := []struct {
+		line  string
+		ok    bool
+		event PlayerEvent
+	}{
+		{
+			line:  "[2025-01-01 12:00:00:000 INFO] Player connected: Steve, xuid: 2535412345678901",
+			ok:    true,
+			event: PlayerEvent{Type: PlayerJoin, Name: "Steve", Xuid: "2535412345678901"},
+		},
+		{
+			line:  "[2025-01-01 12:30:00:000 INFO] Player disconnected: Steve, xuid: 2535412345678901, pfid: 0123456789abcdef",
+			ok:    true,
+			event: PlayerEvent{Type: PlayerLeave, Name: "Steve", Xuid: "2535412345678901"},
+		},
+		{
+			line:  "[2025-01-01 12:00:00:000 INFO] Player connected: Big Alex, xuid: ",
+			ok:    true,
+			event: PlayerEvent{Type: PlayerJoin, Name: "Big Alex"},
+		},
+		{
+			line: "[2025-01-01 12:00:00:000 INFO] Server started.",
+		},
+	}
+	for _, tt := range tests {
+		event, ok := parsePlayerEvent(ConsoleLine{Text: tt.line})
+		if ok != tt.ok {
+			t.Fatalf("%q: expected ok=%v", tt.line, tt.ok)
+		}
+		if ok && (event.Type != tt.event.Type || event.Name != tt.event.Name || event.Xuid != tt.event.Xuid) {
+			t.Fatalf("%q: unexpected event %+v", tt.line, event)
+		}
+	}
+}
+
+func TestPlayerTracker(t *testing.T) {
+	tracker := NewPlayerTracker()
+	events := make([]PlayerEvent, 0)
+	tracker.OnEvent(func(event PlayerEvent) {
+		events = append(events, event)
+	})
+	now := time.Now()
+	tracker.Observe(ConsoleLine{Time: now, Text: "[INFO] Player connected: Steve, xuid: 1"})
+	tracker.Observe(ConsoleLine{Time: now.Add(time.Second), Text: "[INFO] Player connected: Alex, xuid: 2"})
+	online := tracker.Online()
+	if len(online) != 2 || online[0].Name != "Steve" || online[1].Name != "Alex" {
+		t.Fatalf("unexpected online players: %+v", online)
+	}
+	tracker.Observe(ConsoleLine{Time: now, Text: "[INFO] Player disconnected: Steve, xuid: 1, pfid: x"})
+	online = tracker.Online()
+	if len(online) != 1 || online[0].Xuid != "2" {
+		t.Fatalf("unexpected online players: %+v", online)
+	}
+	tracker.Reset(now)
+	if online = tracker.Online(); len(online) != 0 {
+		t.Fatalf("expected no players after reset, got %+v", online)
+	}
+	if len(events) != 4 || events[3].Type != PlayerLeave || events[3].Name != "Alex" {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+}
+
+func TestServer_OnlinePlayers(t *testing.T) {
+	server := testFakeServer(t)
+	err := server.Start()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	_, err = server.process.WaitReady(5 * time.Second)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	_ = server.process.ExecCmd("join", "Steve", "2535412345678901")
+	waitFor(t, 5*time.Second, func() bool {
+		return len(server.OnlinePlayers()) == 1
+	})
+	if player := server.OnlinePlayers()[0]; player.Name != "Steve" || player.Xuid != "2535412345678901" {
+		t.Fatalf("unexpected player: %+v", player)
+	}
+	err = server.Stop()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	waitFor(t, 5*time.Second, func() bool {
+		return len(server.OnlinePlayers()) == 0
+	})
+}
@@ -0,0 +1,479 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/candbright/go-log/log"
+	"github.com/candbright/go-server/internal/mc-server/utils"
+	"github.com/candbright/go-server/pkg/dw"
+	"github.com/pkg/errors"
+)
+
+const (
+	// BackendNative 由面板通过 os/exec 直接托管服务器进程
+	BackendNative = "native"
+	// BackendTmux 在 tmux 会话中运行服务器进程，仅支持 linux
+	BackendTmux = "tmux"
+	// BackendScreen 在 screen 会话中运行服务器进程，仅支持 linux
+	BackendScreen = "screen"
+	// BackendWindow 沿用 windows 下按进程名查找窗口的方式，仅支持 windows
+	BackendWindow = "window"
+)
+
+// DefaultBackend 未配置 mc.process.backend 时使用的会话后端
+const DefaultBackend = BackendNative
+
+const (
+	// DefaultStopTimeout 发送 stop 命令后等待服务器保存并退出的默认时间
+	DefaultStopTimeout = 30 * time.Second
+	stopPollInterval   = 500 * time.Millisecond
+	// watchInterval 无法获得退出通知的后端轮询进程状态的间隔
+	watchInterval = 5 * time.Second
+)
+
+// terminateTimeout 发送 SIGTERM 后等待进程退出的时间，超时后发送 SIGKILL
+var terminateTimeout = 10 * time.Second
+
+const (
+	StopMethodCommand = "command"
+	StopMethodSigterm = "sigterm"
+	StopMethodSigkill = "sigkill"
+)
+
+// SessionState 持久化到服务器目录中的进程会话标识，面板重启后据此重新接管仍在运行的进程
+type SessionState struct {
+	Backend string `json:"backend"`
+	// Session tmux 等终端后端的会话名
+	Session string `json:"session,omitempty"`
+	Pid     int    `json:"pid,omitempty"`
+	// ProcStart 进程启动时间（系统启动以来的时钟数），用于识别 pid 是否已被其他进程复用
+	ProcStart uint64    `json:"proc_start,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// same 判断两个会话标识是否指向同一个进程
+func (state SessionState) same(other SessionState) bool {
+	return state.Backend == other.Backend && state.Session == other.Session &&
+		state.Pid == other.Pid && state.ProcStart == other.ProcStart
+}
+
+// loadSessionState 读取持久化的会话标识
+func loadSessionState(file string) (SessionState, bool) {
+	if file == "" || !Exists(file) {
+		return SessionState{}, false
+	}
+	w, err := dw.Json[SessionState](file)
+	if err != nil {
+		log.WithError(err).Warn("read process state failed")
+		return SessionState{}, false
+	}
+	return w.Data, w.Data.Backend != ""
+}
+
+// SessionBackend 承载 bedrock_server 进程的会话后端，由 NewSessionBackend 按名称创建
+type SessionBackend interface {
+	Start() error
+	// State 返回当前进程的会话标识
+	State() SessionState
+	// Attach 根据会话标识接管已在运行的进程，成功返回 true
+	Attach(state SessionState) bool
+	Active() bool
+	Send(line string) error
+	// Terminate 请求进程退出（SIGTERM），不等待进程结束
+	Terminate() error
+	// Kill 强制结束进程
+	Kill() error
+	// OnOutput 注册输出处理函数，会话每输出一行回调一次
+	OnOutput(handler func(line string))
+}
+
+// BackendConfig 创建会话后端所需的参数
+type BackendConfig struct {
+	// Session tmux、screen 等终端后端的会话名
+	Session  string
+	Dir      string
+	ExecFile string
+}
+
+// ValidateBackend 检查后端名称是否受当前系统支持
+func ValidateBackend(backend string) error {
+	for _, supported := range SupportedBackends() {
+		if backend == supported {
+			return nil
+		}
+	}
+	return errors.Errorf("unsupported process backend [%s], available: %s",
+		backend, strings.Join(SupportedBackends(), ", "))
+}
+
+type Process struct {
+	processId  string
+	rootDir    string
+	backend    string
+	session    SessionBackend
+	supervisor *Supervisor
+	console    *Console
+	// stopTimeout 发送 stop 命令后等待进程退出的时间
+	stopTimeout time.Duration
+	// stateFile 持久化会话标识的文件，为空时不持久化
+	stateFile string
+
+	mu       sync.Mutex
+	stopping bool
+	onExit   func(exit ProcessExit)
+	// watched 本次启动的 watch 读取 stopping 后关闭
+	watched chan struct{}
+	// state 本进程写入状态文件的会话标识
+	state SessionState
+	// status 根据启动日志判断的运行状态，ready 在离开 starting 状态时关闭
+	status ProcessStatus
+	ready  chan struct{}
+}
+
+type ProcessConfig struct {
+	RootDir string
+	// Backend 进程会话后端，可选值见 SupportedBackends，默认 DefaultBackend
+	Backend string
+	// Console 保存进程输出的控制台缓冲，为空时新建
+	Console *Console
+	// StopTimeout 发送 stop 命令后等待进程退出的时间，默认 DefaultStopTimeout
+	StopTimeout time.Duration
+	// StateFile 持久化会话标识的文件路径，用于面板重启后重新接管进程，为空时不持久化
+	StateFile string
+}
+
+func NewProcess(cfg ProcessConfig) *Process {
+	randomId := utils.RandomString(8, utils.AlphaNumCharset)
+	backend := cfg.Backend
+	// 存在上次运行留下的会话标识时沿用其后端和会话名，以便重新接管
+	if state, ok := loadSessionState(cfg.StateFile); ok {
+		backend = state.Backend
+		if state.Session != "" {
+			randomId = strings.TrimPrefix(state.Session, "mc-")
+		}
+	}
+	if backend == "" {
+		backend = DefaultBackend
+	}
+	p := &Process{
+		processId:   randomId,
+		rootDir:     cfg.RootDir,
+		backend:     backend,
+		console:     cfg.Console,
+		stopTimeout: cfg.StopTimeout,
+		stateFile:   cfg.StateFile,
+	}
+	if p.console == nil {
+		p.console = NewConsole(DefaultConsoleSize)
+	}
+	if p.stopTimeout <= 0 {
+		p.stopTimeout = DefaultStopTimeout
+	}
+	backendCfg := BackendConfig{
+		Session:  p.ScreenName(),
+		Dir:      p.rootDir,
+		ExecFile: p.ExecFile(),
+	}
+	session, err := NewSessionBackend(p.backend, backendCfg)
+	if err != nil {
+		log.WithError(err).Warnf("falling back to %s backend", DefaultBackend)
+		p.backend = DefaultBackend
+		session, _ = NewSessionBackend(p.backend, backendCfg)
+	}
+	p.session = session
+	// native 后端能直接获得退出通知和退出码
+	if supervisor, ok := session.(*Supervisor); ok {
+		p.supervisor = supervisor
+	}
+	p.session.OnOutput(func(line string) {
+		p.console.Write(line)
+		p.observe(line)
+	})
+	return p
+}
+
+func (p *Process) Console() *Console {
+	return p.console
+}
+
+func (p *Process) Backend() string {
+	return p.backend
+}
+
+func (p *Process) Active() bool {
+	return p.session.Active()
+}
+
+// ScreenName 返回 tmux、screen 等终端后端使用的会话名
+func (p *Process) ScreenName() string {
+	return fmt.Sprintf("mc-%s", p.processId)
+}
+
+func (p *Process) Start() error {
+	if p.Active() {
+		return errors.New("server process is already running")
+	}
+	// 先进入 starting 状态再启动，避免漏掉启动日志
+	p.setStarting()
+	err := p.session.Start()
+	if err != nil {
+		p.setFailed(err.Error())
+		return err
+	}
+	p.saveState()
+	if _, ok := p.session.(silentBackend); ok {
+		p.setRunning()
+	}
+	go p.watch(p.startWatch())
+	return nil
+}
+
+// Stop 优雅关闭服务器，详见 Shutdown
+func (p *Process) Stop() error {
+	_, err := p.Shutdown()
+	return err
+}
+
+func (p *Process) Restart() error {
+	err := p.Stop()
+	if err != nil {
+		return err
+	}
+	err = p.Start()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// ExecCmd 向服务器控制台发送一条命令，命令中不能包含换行
+func (p *Process) ExecCmd(arg ...string) error {
+	if !p.Active() {
+		return errors.New("server process is not running")
+	}
+	command := strings.Join(arg, " ")
+	// 换行会让控制台把剩余部分当作另一条命令执行
+	if strings.ContainsAny(command, "\r\n") {
+		return errors.New("command must not contain line breaks")
+	}
+	return p.session.Send(command)
+}
+
+// StopResult 描述一次关闭服务器的过程
+type StopResult struct {
+	// Method 最终使进程退出的方式：command、sigterm 或 sigkill
+	Method     string `json:"method"`
+	DurationMs int64  `json:"duration_ms"`
+	// ExitCode 进程退出码，无法获取时为 -1
+	ExitCode int `json:"exit_code"`
+}
+
+// ProcessExit 描述一次进程退出
+type ProcessExit struct {
+	Time time.Time
+	// ExitCode 进程退出码，无法获取时为 -1
+	ExitCode int
+	// Requested 为 true 表示退出由面板主动发起（Stop、Shutdown 等）
+	Requested bool
+}
+
+// OnExit 设置进程退出时的回调
+func (p *Process) OnExit(handler func(exit ProcessExit)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onExit = handler
+}
+
+func (p *Process) setStopping(stopping bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stopping = stopping
+}
+
+// saveState 将当前会话标识写入状态文件
+func (p *Process) saveState() {
+	if p.stateFile == "" {
+		return
+	}
+	state := p.session.State()
+	w, err := dw.JsonOrDefault[SessionState](p.stateFile, state)
+	if err == nil {
+		w.Data = state
+		err = w.Write()
+	}
+	if err != nil {
+		log.WithError(err).Warn("save process state failed")
+		return
+	}
+	p.mu.Lock()
+	p.state = state
+	p.mu.Unlock()
+}
+
+// clearState 删除状态文件。文件已被新进程（如 Reload 后启动的进程）覆盖时保留
+func (p *Process) clearState() {
+	if p.stateFile == "" {
+		return
+	}
+	p.mu.Lock()
+	state := p.state
+	p.state = SessionState{}
+	p.mu.Unlock()
+	if current, ok := loadSessionState(p.stateFile); ok && state.Backend != "" && !current.same(state) {
+		return
+	}
+	if err := os.Remove(p.stateFile); err != nil && !os.IsNotExist(err) {
+		log.WithError(err).Warn("remove process state failed")
+	}
+}
+
+// Attach 根据状态文件接管上次启动后仍在运行的进程，成功返回 true
+func (p *Process) Attach() bool {
+	// 终端后端的会话在接管前就已存在，不能以 Active 判断是否已接管
+	p.mu.Lock()
+	owned := p.state.Backend != ""
+	p.mu.Unlock()
+	if owned && p.Active() {
+		return true
+	}
+	state, ok := loadSessionState(p.stateFile)
+	if !ok {
+		return false
+	}
+	if state.Backend != p.backend || !p.session.Attach(state) {
+		p.clearState()
+		return false
+	}
+	p.mu.Lock()
+	p.state = state
+	p.mu.Unlock()
+	// 接管的进程已完成启动
+	p.setRunning()
+	go p.watch(p.startWatch())
+	return true
+}
+
+// startWatch 为本次启动创建 watched
+func (p *Process) startWatch() chan struct{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.watched = make(chan struct{})
+	return p.watched
+}
+
+// waitWatched 等待 watch 处理完上一次退出，避免紧接着的 Start 与上一次的退出处理交错，
+// 使新进程的 stopping 被上一次退出清除
+func (p *Process) waitWatched() {
+	p.mu.Lock()
+	watched := p.watched
+	p.mu.Unlock()
+	if watched != nil {
+		<-watched
+	}
+}
+
+// watch 等待本次启动的进程退出并通知 OnExit 回调，读取 stopping 后关闭 watched
+func (p *Process) watch(watched chan struct{}) {
+	if p.supervisor != nil {
+		if done := p.supervisor.Done(); done != nil {
+			<-done
+		}
+	} else {
+		for p.session.Active() {
+			time.Sleep(watchInterval)
+		}
+	}
+	exit := ProcessExit{
+		Time:     time.Now(),
+		ExitCode: -1,
+	}
+	if p.supervisor != nil {
+		exit.ExitCode = p.supervisor.ExitCode()
+	}
+	p.clearState()
+	p.exited(exit)
+	p.mu.Lock()
+	exit.Requested = p.stopping
+	p.stopping = false
+	handler := p.onExit
+	p.mu.Unlock()
+	close(watched)
+	if handler != nil {
+		handler(exit)
+	}
+}
+
+// Shutdown 依次尝试 stop 命令、SIGTERM、SIGKILL 关闭服务器：
+// stop 命令让服务器保存世界后退出，超过 stopTimeout 仍未退出时发送 SIGTERM，再超过 terminateTimeout 则强制结束。
+func (p *Process) Shutdown() (StopResult, error) {
+	result := StopResult{ExitCode: -1}
+	if !p.Active() {
+		return result, errors.New("server process is not running")
+	}
+	p.setStopping(true)
+	start := time.Now()
+	defer func() {
+		result.DurationMs = time.Since(start).Milliseconds()
+		if !p.Active() {
+			p.waitWatched()
+			if p.supervisor != nil {
+				result.ExitCode = p.supervisor.ExitCode()
+			}
+		}
+	}()
+
+	result.Method = StopMethodCommand
+	err := p.session.Send("stop")
+	if err != nil {
+		log.WithError(err).Warn("send stop command failed")
+	} else if p.waitExit(p.stopTimeout) {
+		return result, nil
+	}
+
+	result.Method = StopMethodSigterm
+	log.WithField("timeout", p.stopTimeout).Warn("server did not stop in time, sending SIGTERM")
+	err = p.session.Terminate()
+	if err != nil {
+		log.WithError(err).Warn("terminate server process failed")
+	} else if p.waitExit(terminateTimeout) {
+		return result, nil
+	}
+
+	result.Method = StopMethodSigkill
+	log.Warn("server did not exit after SIGTERM, killing it")
+	err = p.session.Kill()
+	if err != nil && p.Active() {
+		return result, err
+	}
+	return result, nil
+}
+
+// waitExit 等待进程退出，超时返回 false
+func (p *Process) waitExit(timeout time.Duration) bool {
+	if p.supervisor != nil {
+		done := p.supervisor.Done()
+		if done == nil {
+			return true
+		}
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case <-done:
+			return true
+		case <-timer.C:
+			return false
+		}
+	}
+	deadline := time.Now().Add(timeout)
+	for p.session.Active() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(stopPollInterval)
+	}
+	return true
+}
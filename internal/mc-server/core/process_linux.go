@@ -1,81 +1,38 @@
 package core
 
-import (
-	"fmt"
-	"github.com/candbright/go-server/internal/mc-server/utils"
-	"github.com/pkg/errors"
-	"path"
-)
-
-type Process struct {
-	processId string
-	rootDir   string
-	screen    Tmux
-}
-
-type ProcessConfig struct {
-	RootDir string
-}
-
-func NewProcess(cfg ProcessConfig) *Process {
-	randomId := utils.RandomString(8)
-	p := &Process{
-		processId: randomId,
-		rootDir:   cfg.RootDir,
-		screen:    Tmux{Name: fmt.Sprintf("mc-%s", randomId)},
+import "path"
+
+// SupportedBackends 返回当前系统支持的会话后端
+func SupportedBackends() []string {
+	return []string{BackendNative, BackendTmux, BackendScreen}
+}
+
+// NewSessionBackend 按名称创建会话后端
+func NewSessionBackend(backend string, cfg BackendConfig) (SessionBackend, error) {
+	switch backend {
+	case BackendNative:
+		return NewSupervisor(SupervisorConfig{
+			Dir:      cfg.Dir,
+			ExecFile: cfg.ExecFile,
+			Env:      []string{"LD_LIBRARY_PATH=."},
+			IODir:    cfg.Dir,
+		}), nil
+	case BackendTmux:
+		return &tmuxSession{
+			Tmux:     Tmux{Name: cfg.Session},
+			dir:      cfg.Dir,
+			execFile: cfg.ExecFile,
+		}, nil
+	case BackendScreen:
+		return &screenSession{
+			Screen:   Screen{Name: cfg.Session},
+			dir:      cfg.Dir,
+			execFile: cfg.ExecFile,
+		}, nil
 	}
-
-	return p
-}
-
-func (p *Process) Active() bool {
-	return p.screen.Exists()
+	return nil, ValidateBackend(backend)
 }
 
 func (p *Process) ExecFile() string {
 	return path.Join(p.rootDir, "bedrock_server")
 }
-
-func (p *Process) ScreenName() string {
-	return fmt.Sprintf("mc-%s", p.processId)
-}
-
-func (p *Process) Start() error {
-	if !p.Active() {
-		err := p.screen.Create()
-		if err != nil {
-			return err
-		}
-		err = p.screen.ExecCmd("LD_LIBRARY_PATH=.", p.ExecFile())
-		if err != nil {
-			return err
-		}
-	}
-	return errors.New("server process is already running")
-}
-
-func (p *Process) Stop() error {
-	if p.Active() {
-		return p.screen.Exit()
-	}
-	return errors.New("server process is not running")
-}
-
-func (p *Process) Restart() error {
-	err := p.Stop()
-	if err != nil {
-		return err
-	}
-	err = p.Start()
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
-func (p *Process) ExecCmd(arg ...string) error {
-	if !p.Active() {
-		return errors.New("server process is not running")
-	}
-	return p.screen.ExecCmd(arg...)
-}
@@ -1,9 +1,105 @@
 package core
 
 import (
+	"fmt"
+	"os"
+	"path"
+	"runtime"
 	"testing"
+	"time"
 )
 
+// fakeServerScript 模拟 bedrock_server 的控制台行为，用于不依赖真实服务器的进程测试
+const fakeServerScript = `#!/bin/sh
+echo "[INFO] Starting Server"
+echo "[INFO] IPv4 supported, port: 19132: Used for gameplay and LAN discovery"
+echo "[INFO] IPv6 supported, port: 19133: Used for gameplay"
+echo "[INFO] Server started."
+while read line; do
+	case "$line" in
+	stop) echo "[INFO] Stopping server..."; echo "Quit correctly"; exit 0 ;;
+	crash) echo "[ERROR] Crashing"; exit 3 ;;
+	bogus*) echo "[ERROR] Unknown command: bogus. Please check that the command exists and that you have permission to use it." ;;
+	join\ *) set -- $line; echo "[INFO] Player connected: $2, xuid: $3" ;;
+	leave\ *) set -- $line; echo "[INFO] Player disconnected: $2, xuid: $3, pfid: 0123456789abcdef" ;;
+	noisy) echo "[INFO] Player connected: Alex, xuid: 2535400000000002"; echo "[INFO] Running AutoCompaction..."; echo "[INFO] noisy" ;;
+	gamerule) echo "commandBlockOutput = true, doDaylightCycle = false, keepInventory = false, randomTickSpeed = 1" ;;
+	save\ query)
+		# 模拟暂停存档后服务器仍向 LevelDB 文件追加数据，列出的长度为追加前的长度
+		f="worlds/Bedrock level/db/000005.ldb"; n=1024
+		if [ -f "$f" ]; then n=$(wc -c < "$f" | tr -d ' '); echo "appended after save hold" >> "$f"; fi
+		echo "Data saved. Files are now ready to be copied."
+		echo "Bedrock level/db/000005.ldb:$n, Bedrock level/db/CURRENT:16" ;;
+	*) echo "[INFO] $line" ;;
+	esac
+done
+`
+
+// stubbornServerScript 忽略 stop 命令，用于测试关闭超时后的信号升级
+const stubbornServerScript = `#!/bin/sh
+%s
+echo "[INFO] Server started."
+while true; do
+	read line || sleep 1
+done
+`
+
+// writeFakeServer 在 dir 下写入假的 bedrock_server 可执行文件
+func writeFakeServer(t *testing.T, dir string, script string) {
+	if runtime.GOOS != "linux" {
+		t.Skip("fake bedrock_server requires a posix shell")
+	}
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	err = os.WriteFile(path.Join(dir, "bedrock_server"), []byte(script), 0755)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+}
+
+func testProcess(t *testing.T, backend string) *Process {
+	return testProcessWithScript(t, backend, fakeServerScript)
+}
+
+func testProcessWithScript(t *testing.T, backend string, script string) *Process {
+	dir := t.TempDir()
+	writeFakeServer(t, dir, script)
+	p := NewProcess(ProcessConfig{
+		RootDir: dir,
+		Backend: backend,
+	})
+	t.Cleanup(func() {
+		if p.Active() {
+			_ = p.session.Kill()
+		}
+	})
+	return p
+}
+
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) {
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met within %v", timeout)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// waitStarted 等待假服务器输出启动完成日志
+func waitStarted(t *testing.T, p *Process) {
+	waitFor(t, 5*time.Second, func() bool {
+		for _, line := range p.Console().Lines(0) {
+			if line.Text == "[INFO] Server started." {
+				return true
+			}
+		}
+		return false
+	})
+}
+
 func TestProcess_Active(t *testing.T) {
 	process := testServer(t).process
 	active := process.Active()
@@ -11,21 +107,170 @@ func TestProcess_Active(t *testing.T) {
 }
 
 func TestProcess_Start(t *testing.T) {
-	process := testServer(t).process
+	process := testProcess(t, BackendNative)
 	err := process.Start()
 	if err != nil {
 		t.Fatalf("%+v", err)
 	}
-	active := process.Active()
-	t.Log(active)
+	if !process.Active() {
+		t.Fatal("process should be active after start")
+	}
+	if err = process.Start(); err == nil {
+		t.Fatal("starting a running process should fail")
+	}
 }
 
 func TestProcess_Stop(t *testing.T) {
-	process := testServer(t).process
-	err := process.Stop()
+	process := testProcess(t, BackendNative)
+	if err := process.Stop(); err == nil {
+		t.Fatal("stopping a stopped process should fail")
+	}
+	err := process.Start()
 	if err != nil {
 		t.Fatalf("%+v", err)
 	}
-	active := process.Active()
-	t.Log(active)
+	err = process.Stop()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if process.Active() {
+		t.Fatal("process should not be active after stop")
+	}
+}
+
+func TestProcess_ExecCmd(t *testing.T) {
+	process := testProcess(t, BackendNative)
+	lines := make(chan string, 16)
+	process.supervisor.OnOutput(func(line string) {
+		lines <- line
+	})
+	if err := process.ExecCmd("list"); err == nil {
+		t.Fatal("exec on a stopped process should fail")
+	}
+	err := process.Start()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err = process.ExecCmd("say", "hi\nstop"); err == nil {
+		t.Fatal("command with a line break should be rejected")
+	}
+	err = process.ExecCmd("allowlist", "add", "Steve")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case line := <-lines:
+			if line == "[INFO] allowlist add Steve" {
+				return
+			}
+		case <-timeout:
+			t.Fatal("command echo not received")
+		}
+	}
+}
+
+func TestProcess_ExitDetected(t *testing.T) {
+	process := testProcess(t, BackendNative)
+	err := process.Start()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	err = process.ExecCmd("stop")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	waitFor(t, 5*time.Second, func() bool {
+		return !process.Active()
+	})
+	if code := process.supervisor.ExitCode(); code != 0 {
+		t.Fatalf("expected exit code 0, got %d", code)
+	}
+}
+
+func TestProcess_Shutdown(t *testing.T) {
+	defer func(timeout time.Duration) {
+		terminateTimeout = timeout
+	}(terminateTimeout)
+	terminateTimeout = 500 * time.Millisecond
+	tests := []struct {
+		name   string
+		script string
+		method string
+	}{
+		{name: "stop command", script: fakeServerScript, method: StopMethodCommand},
+		{name: "sigterm", script: fmt.Sprintf(stubbornServerScript, ""), method: StopMethodSigterm},
+		{name: "sigkill", script: fmt.Sprintf(stubbornServerScript, "trap '' TERM"), method: StopMethodSigkill},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			process := testProcessWithScript(t, BackendNative, tt.script)
+			process.stopTimeout = 500 * time.Millisecond
+			err := process.Start()
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			waitStarted(t, process)
+			result, err := process.Shutdown()
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if result.Method != tt.method {
+				t.Fatalf("expected method %s, got %s", tt.method, result.Method)
+			}
+			if process.Active() {
+				t.Fatal("process should not be active after shutdown")
+			}
+		})
+	}
+}
+
+func TestProcess_Attach(t *testing.T) {
+	dir := t.TempDir()
+	writeFakeServer(t, dir, fakeServerScript)
+	cfg := ProcessConfig{
+		RootDir:   dir,
+		StateFile: path.Join(dir, "process.json"),
+	}
+	first := NewProcess(cfg)
+	err := first.Start()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	t.Cleanup(func() {
+		if first.Active() {
+			_ = first.session.Kill()
+		}
+	})
+	waitStarted(t, first)
+
+	// 模拟面板重启：以相同配置重新创建进程并接管
+	second := NewProcess(cfg)
+	if !second.Attach() || !second.Active() {
+		t.Fatal("expected to attach to the running process")
+	}
+	if second.supervisor.Pid() != first.supervisor.Pid() {
+		t.Fatal("attached to a different process")
+	}
+	lines, err := second.ExecCmdOutput(2*time.Second, "list")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if len(lines) != 1 || lines[0] != "[INFO] list" {
+		t.Fatalf("unexpected output after attach: %q", lines)
+	}
+	result, err := second.Shutdown()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if result.Method != StopMethodCommand {
+		t.Fatalf("expected stop command to work after attach, got %s", result.Method)
+	}
+	waitFor(t, 5*time.Second, func() bool {
+		return !Exists(cfg.StateFile)
+	})
+	if NewProcess(cfg).Attach() {
+		t.Fatal("should not attach to an exited process")
+	}
 }
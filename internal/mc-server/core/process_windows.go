@@ -2,68 +2,79 @@ package core
 
 import (
 	"path"
-	"strings"
 
 	"github.com/pkg/errors"
 )
 
-type Process struct {
-	rootDir string
-	window  Window
+// SupportedBackends 返回当前系统支持的会话后端
+func SupportedBackends() []string {
+	return []string{BackendNative, BackendWindow}
 }
 
-type ProcessConfig struct {
-	RootDir string
+// NewSessionBackend 按名称创建会话后端
+func NewSessionBackend(backend string, cfg BackendConfig) (SessionBackend, error) {
+	switch backend {
+	case BackendNative:
+		return NewSupervisor(SupervisorConfig{
+			Dir:      cfg.Dir,
+			ExecFile: cfg.ExecFile,
+		}), nil
+	case BackendWindow:
+		return &windowSession{
+			window:   Window{title: "bedrock_server.exe"},
+			execFile: cfg.ExecFile,
+		}, nil
+	}
+	return nil, ValidateBackend(backend)
 }
 
-func NewProcess(cfg ProcessConfig) *Process {
-	p := &Process{
-		rootDir: cfg.RootDir,
-		window:  Window{title: "bedrock_server.exe"},
-	}
-	return p
+func (p *Process) ExecFile() string {
+	return path.Join(p.rootDir, "bedrock_server.exe")
 }
 
-func (p *Process) Active() bool {
-	return p.window.IsRunning()
+// windowSession 将 Window 适配为进程会话后端
+type windowSession struct {
+	window   Window
+	execFile string
 }
 
-func (p *Process) ExecFile() string {
-	return path.Join(p.rootDir, "bedrock_server.exe")
+func (s *windowSession) Start() error {
+	return errors.WithStack(Command(s.execFile).Start())
 }
 
-func (p *Process) Start() error {
-	if !p.Active() {
-		err := Command(p.ExecFile()).Run()
-		if err != nil {
-			return errors.WithStack(err)
-		}
+func (s *windowSession) State() SessionState {
+	state := SessionState{Backend: BackendWindow}
+	if entry, err := s.window.ProcessEntry(); err == nil {
+		state.Pid = int(entry.ProcessID)
 	}
-	return errors.New("server process is already running")
+	return state
 }
 
-func (p *Process) Stop() error {
-	if p.Active() {
-		return p.window.Close()
-	}
-	return errors.New("server process is not running")
+// Attach 窗口后端按进程名查找服务器进程，进程仍在运行即可接管
+func (s *windowSession) Attach(SessionState) bool {
+	return s.window.IsRunning()
 }
 
-func (p *Process) Restart() error {
-	err := p.Stop()
-	if err != nil {
-		return err
-	}
-	err = p.Start()
-	if err != nil {
-		return err
-	}
-	return nil
+func (s *windowSession) Active() bool {
+	return s.window.IsRunning()
 }
 
-func (p *Process) ExecCmd(arg ...string) error {
-	if !p.Active() {
-		return errors.New("server process is not running")
-	}
-	return p.window.ExecuteCommand(strings.Join(arg, " "))
+func (s *windowSession) Send(line string) error {
+	return s.window.ExecuteCommand(line)
+}
+
+func (s *windowSession) Terminate() error {
+	return s.window.Close()
+}
+
+func (s *windowSession) Kill() error {
+	return s.window.Close()
+}
+
+// OnOutput 窗口后端无法读取服务器输出
+func (s *windowSession) OnOutput(func(line string)) {
+}
+
+// silent 窗口后端无法读取输出，启动后直接视为运行中
+func (s *windowSession) silent() {
 }
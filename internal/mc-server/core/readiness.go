@@ -0,0 +1,189 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	StatusStopped  = "stopped"
+	StatusStarting = "starting"
+	StatusRunning  = "running"
+	StatusFailed   = "failed"
+)
+
+const (
+	// DefaultReadyTimeout 阻塞启动时等待服务器就绪的默认时间
+	DefaultReadyTimeout = 2 * time.Minute
+	// MaxReadyTimeout 允许调用方指定的最长等待时间
+	MaxReadyTimeout = 10 * time.Minute
+)
+
+// serverStartedLine bedrock 世界加载完成、可以接受连接时输出的日志
+const serverStartedLine = "Server started."
+
+// portRegex 匹配启动时输出的监听端口，如 "IPv4 supported, port: 19132: Used for gameplay and LAN discovery"
+var portRegex = regexp.MustCompile(`(IPv4|IPv6) supported, port: (\d+)`)
+
+// startupFailurePatterns bedrock 无法继续启动时的输出片段。
+// 只匹配致命错误，加载资源包等失败的警告不影响启动，不能算作启动失败
+var startupFailurePatterns = []string{
+	"Network port occupied",
+	"can't start server",
+	"Exiting program",
+}
+
+// silentBackend 由无法读取输出的后端实现，这类后端启动后直接视为运行中
+type silentBackend interface {
+	silent()
+}
+
+// ProcessStatus 根据启动日志判断的服务器运行状态
+type ProcessStatus struct {
+	// State 取值为 stopped、starting、running、failed
+	State string `json:"state"`
+	// Reason 启动失败的原因
+	Reason   string    `json:"reason,omitempty"`
+	IPv4Port int       `json:"ipv4_port,omitempty"`
+	IPv6Port int       `json:"ipv6_port,omitempty"`
+	Since    time.Time `json:"since"`
+}
+
+// StartupError 表示服务器启动失败
+type StartupError struct {
+	Reason string
+}
+
+func (err StartupError) Error() string {
+	return fmt.Sprintf("server failed to start: %s", err.Reason)
+}
+
+// Status 返回服务器当前的运行状态
+func (p *Process) Status() ProcessStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	status := p.status
+	if status.State == "" {
+		status.State = StatusStopped
+	}
+	return status
+}
+
+func (p *Process) setStarting() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closeReadyLocked()
+	p.status = ProcessStatus{
+		State: StatusStarting,
+		Since: time.Now(),
+	}
+	p.ready = make(chan struct{})
+}
+
+func (p *Process) setRunning() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status.State = StatusRunning
+	p.status.Reason = ""
+	p.status.Since = time.Now()
+	p.closeReadyLocked()
+}
+
+func (p *Process) setFailed(reason string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.setFailedLocked(reason)
+}
+
+func (p *Process) setFailedLocked(reason string) {
+	p.status.State = StatusFailed
+	p.status.Reason = reason
+	p.status.Since = time.Now()
+	p.closeReadyLocked()
+}
+
+func (p *Process) closeReadyLocked() {
+	if p.ready == nil {
+		return
+	}
+	select {
+	case <-p.ready:
+	default:
+		close(p.ready)
+	}
+}
+
+// observe 解析启动阶段的输出，识别监听端口、启动完成和启动失败
+func (p *Process) observe(line string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.status.State != StatusStarting {
+		return
+	}
+	if match := portRegex.FindStringSubmatch(line); match != nil {
+		port, _ := strconv.Atoi(match[2])
+		if match[1] == "IPv4" {
+			p.status.IPv4Port = port
+		} else {
+			p.status.IPv6Port = port
+		}
+	}
+	for _, pattern := range startupFailurePatterns {
+		if strings.Contains(line, pattern) {
+			p.setFailedLocked(strings.TrimSpace(line))
+			return
+		}
+	}
+	if strings.Contains(line, serverStartedLine) {
+		p.status.State = StatusRunning
+		p.status.Since = time.Now()
+		p.closeReadyLocked()
+	}
+}
+
+// exited 进程退出后更新状态：启动阶段意外退出视为启动失败，失败状态保留失败原因
+func (p *Process) exited(exit ProcessExit) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch {
+	case p.status.State == StatusStarting && !p.stopping:
+		p.setFailedLocked(fmt.Sprintf("process exited during startup (exit code %d)", exit.ExitCode))
+	case p.status.State == StatusFailed:
+	default:
+		p.status.State = StatusStopped
+		p.status.Since = exit.Time
+		p.closeReadyLocked()
+	}
+}
+
+// WaitReady 等待本次启动离开 starting 状态，启动失败时返回 StartupError，超时返回错误
+func (p *Process) WaitReady(timeout time.Duration) (ProcessStatus, error) {
+	if timeout <= 0 {
+		timeout = DefaultReadyTimeout
+	}
+	if timeout > MaxReadyTimeout {
+		timeout = MaxReadyTimeout
+	}
+	p.mu.Lock()
+	ready := p.ready
+	p.mu.Unlock()
+	if ready != nil {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case <-ready:
+		case <-timer.C:
+			return p.Status(), errors.Errorf("server did not become ready within %v", timeout)
+		}
+	}
+	status := p.Status()
+	if status.State == StatusFailed {
+		return status, StartupError{Reason: status.Reason}
+	}
+	return status, nil
+}
@@ -0,0 +1,134 @@
+package core
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProcess_Readiness(t *testing.T) {
+	process := testProcess(t, BackendNative)
+	if status := process.Status(); status.State != StatusStopped {
+		t.Fatalf("expected stopped before start, got %s", status.State)
+	}
+	err := process.Start()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	status, err := process.WaitReady(5 * time.Second)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if status.State != StatusRunning {
+		t.Fatalf("expected running, got %s", status.State)
+	}
+	if status.IPv4Port != 19132 || status.IPv6Port != 19133 {
+		t.Fatalf("unexpected ports: %+v", status)
+	}
+	err = process.Stop()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	waitFor(t, 5*time.Second, func() bool {
+		return process.Status().State == StatusStopped
+	})
+}
+
+func TestProcess_ReadinessAfterWarning(t *testing.T) {
+	// 加载资源包失败等警告之后仍能正常完成启动
+	process := testProcessWithScript(t, BackendNative, `#!/bin/sh
+echo "[INFO] Starting Server"
+echo "[WARN] Failed to load pack manifest: behavior_packs/broken/manifest.json"
+echo "[INFO] Server started."
+while read line; do
+	case "$line" in
+	stop) exit 0 ;;
+	esac
+done
+`)
+	err := process.Start()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	status, err := process.WaitReady(5 * time.Second)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if status.State != StatusRunning {
+		t.Fatalf("expected running, got %+v", status)
+	}
+	if err = process.Stop(); err != nil {
+		t.Fatalf("%+v", err)
+	}
+}
+
+func TestProcess_ReadinessFailure(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		reason string
+	}{
+		{
+			name: "port occupied",
+			script: `#!/bin/sh
+echo "[INFO] Starting Server"
+echo "[ERROR] Network port occupied, can't start server."
+exit 1
+`,
+			reason: "Network port occupied",
+		},
+		{
+			name: "exit during startup",
+			script: `#!/bin/sh
+echo "[INFO] Starting Server"
+exit 2
+`,
+			reason: "exit code 2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			process := testProcessWithScript(t, BackendNative, tt.script)
+			err := process.Start()
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			status, err := process.WaitReady(5 * time.Second)
+			var startupErr StartupError
+			if !errors.As(err, &startupErr) {
+				t.Fatalf("expected startup error, got %v", err)
+			}
+			if status.State != StatusFailed || !strings.Contains(status.Reason, tt.reason) {
+				t.Fatalf("unexpected status: %+v", status)
+			}
+			// 进程退出后仍保留失败状态和原因
+			waitFor(t, 5*time.Second, func() bool {
+				return !process.Active()
+			})
+			if status = process.Status(); status.State != StatusFailed {
+				t.Fatalf("failed status should be kept after exit, got %s", status.State)
+			}
+		})
+	}
+}
+
+func TestProcess_ReadinessTimeout(t *testing.T) {
+	process := testProcessWithScript(t, BackendNative, `#!/bin/sh
+echo "[INFO] Starting Server"
+while true; do
+	read line || sleep 1
+done
+`)
+	err := process.Start()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	status, err := process.WaitReady(300 * time.Millisecond)
+	if err == nil {
+		t.Fatal("expected timeout error")
+	}
+	if status.State != StatusStarting {
+		t.Fatalf("expected starting, got %s", status.State)
+	}
+}
@@ -0,0 +1,257 @@
+package core
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/candbright/go-log/log"
+	"github.com/candbright/go-server/pkg/dw"
+	"github.com/pkg/errors"
+)
+
+const (
+	// BusyPostpone 到点时服务器正忙则推迟重启
+	BusyPostpone = "postpone"
+	// BusySkip 到点时服务器正忙则跳过本次重启
+	BusySkip = "skip"
+)
+
+// DefaultRestartWarnings 默认在重启前 10 分钟、5 分钟、1 分钟和 10 秒发送提醒
+var DefaultRestartWarnings = []int{600, 300, 60, 10}
+
+// RestartSchedule 按 cron 表达式定时重启服务器的配置
+type RestartSchedule struct {
+	Enabled bool   `json:"enabled"`
+	Cron    string `json:"cron"`
+	// Warnings 重启前多少秒在游戏内发送倒计时提醒
+	Warnings []int `json:"warnings"`
+	// BusyAction 到点时服务器正忙（见 Server.Busy）的处理方式：postpone（默认）或 skip
+	BusyAction string `json:"busy_action"`
+	// PostponeMinutes 每次推迟的分钟数，默认 5
+	PostponeMinutes int `json:"postpone_minutes"`
+	// MaxPostpones 最多推迟的次数，超过后跳过本次重启，默认 3
+	MaxPostpones int `json:"max_postpones"`
+}
+
+// Validate 校验配置并补全默认值，提醒时间按从早到晚排序
+func (schedule *RestartSchedule) Validate() error {
+	if schedule.Enabled || schedule.Cron != "" {
+		if _, err := ParseCron(schedule.Cron); err != nil {
+			return err
+		}
+	}
+	if schedule.Warnings == nil {
+		schedule.Warnings = append([]int{}, DefaultRestartWarnings...)
+	}
+	for _, warning := range schedule.Warnings {
+		if warning <= 0 {
+			return errors.Errorf("warning [%d] must be a positive number of seconds", warning)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(schedule.Warnings)))
+	if schedule.BusyAction == "" {
+		schedule.BusyAction = BusyPostpone
+	}
+	if schedule.BusyAction != BusyPostpone && schedule.BusyAction != BusySkip {
+		return errors.Errorf("unsupported busy action [%s]", schedule.BusyAction)
+	}
+	if schedule.PostponeMinutes <= 0 {
+		schedule.PostponeMinutes = 5
+	}
+	if schedule.MaxPostpones <= 0 {
+		schedule.MaxPostpones = 3
+	}
+	return nil
+}
+
+// formatCountdown 将秒数格式化为提醒中的剩余时间
+func formatCountdown(seconds int) string {
+	unit, value := "second", seconds
+	if seconds >= 60 && seconds%60 == 0 {
+		unit, value = "minute", seconds/60
+	}
+	if value != 1 {
+		unit += "s"
+	}
+	return fmt.Sprintf("%d %s", value, unit)
+}
+
+func (server *Server) RestartScheduleFilePath() string {
+	return path.Join(server.rootDir, "restart_schedule.json")
+}
+
+func (server *Server) RestartSchedule() (RestartSchedule, error) {
+	w, err := dw.JsonOrDefault[RestartSchedule](server.RestartScheduleFilePath(), RestartSchedule{})
+	if err != nil {
+		return RestartSchedule{}, err
+	}
+	schedule := w.Data
+	if err = schedule.Validate(); err != nil {
+		return RestartSchedule{}, err
+	}
+	return schedule, nil
+}
+
+// SetRestartSchedule 保存定时重启配置并按新配置重新调度
+func (server *Server) SetRestartSchedule(schedule RestartSchedule) (RestartSchedule, error) {
+	if err := schedule.Validate(); err != nil {
+		return RestartSchedule{}, err
+	}
+	w, err := dw.JsonOrDefault[RestartSchedule](server.RestartScheduleFilePath(), RestartSchedule{})
+	if err != nil {
+		return RestartSchedule{}, err
+	}
+	w.Data = schedule
+	if err = w.Write(); err != nil {
+		return RestartSchedule{}, err
+	}
+	server.scheduleRestarts(schedule)
+	return schedule, nil
+}
+
+// NextScheduledRestart 返回下一次定时重启的时间，未启用时返回零值
+func (server *Server) NextScheduledRestart() time.Time {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	return server.nextRestart
+}
+
+// Busy 判断服务器是否正在下载、备份、被复制、升级或回滚，返回正在进行的任务
+func (server *Server) Busy() (string, bool) {
+	if server.Downloading() {
+		return "download", true
+	}
+	if server.backingUp.Load() {
+		return "backup", true
+	}
+	// 复制期间源服务器的存档处于暂停状态
+	if server.cloning.Load() > 0 {
+		return "clone", true
+	}
+	if status, ok := server.UpgradeStatus(); ok && status.State == JobRunning {
+		return "upgrade", true
+	}
+	if _, ok := server.RollingBack(); ok {
+		return "rollback", true
+	}
+	return "", false
+}
+
+// scheduleRestarts 停止已有的调度，并在配置启用时开始新的调度
+func (server *Server) scheduleRestarts(schedule RestartSchedule) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if server.restartStop != nil {
+		close(server.restartStop)
+		server.restartStop = nil
+	}
+	server.nextRestart = time.Time{}
+	if !schedule.Enabled {
+		return
+	}
+	cron, err := ParseCron(schedule.Cron)
+	if err != nil {
+		return
+	}
+	stop := make(chan struct{})
+	server.restartStop = stop
+	go server.runRestartSchedule(schedule, cron, stop)
+}
+
+func (server *Server) setNextRestart(at time.Time, stop chan struct{}) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	// 调度已被替换时不覆盖新调度的时间
+	if server.restartStop == stop {
+		server.nextRestart = at
+	}
+}
+
+func (server *Server) runRestartSchedule(schedule RestartSchedule, cron *CronSchedule, stop chan struct{}) {
+	for {
+		at := cron.Next(time.Now())
+		if at.IsZero() {
+			return
+		}
+		server.setNextRestart(at, stop)
+		if !server.countdownAndRestart(schedule, at, stop) {
+			return
+		}
+	}
+}
+
+// countdownAndRestart 在 at 之前按配置发送倒计时提醒，到点后优雅重启服务器。
+// 服务器正忙时按 BusyAction 推迟或跳过；调度被取消时返回 false。
+func (server *Server) countdownAndRestart(schedule RestartSchedule, at time.Time, stop chan struct{}) bool {
+	logger := log.WithField("server_id", server.id)
+	for postpones := 0; ; postpones++ {
+		for _, warning := range schedule.Warnings {
+			warnAt := at.Add(-time.Duration(warning) * time.Second)
+			if warnAt.Before(time.Now()) {
+				continue
+			}
+			if !sleepUntil(warnAt, stop) {
+				return false
+			}
+			server.announceRestart(fmt.Sprintf("Server will restart in %s", formatCountdown(warning)))
+		}
+		if !sleepUntil(at, stop) {
+			return false
+		}
+		task, busy := server.Busy()
+		if !busy {
+			server.scheduledRestart()
+			return true
+		}
+		if schedule.BusyAction == BusySkip || postpones >= schedule.MaxPostpones {
+			logger.WithField("task", task).Warn("server is busy, skipping scheduled restart")
+			server.announceRestart("Scheduled restart cancelled")
+			return true
+		}
+		at = at.Add(time.Duration(schedule.PostponeMinutes) * time.Minute)
+		server.setNextRestart(at, stop)
+		logger.WithField("task", task).Infof("server is busy, postponing scheduled restart to %s", at.Format(time.RFC3339))
+		server.announceRestart(fmt.Sprintf("Scheduled restart postponed by %s", formatCountdown(schedule.PostponeMinutes*60)))
+	}
+}
+
+// announceRestart 服务器运行时在游戏内广播重启提醒
+func (server *Server) announceRestart(message string) {
+	if !server.Active() {
+		return
+	}
+	if err := server.Broadcast(BroadcastSay, message, ""); err != nil {
+		log.WithError(err).WithField("server_id", server.id).Warn("send restart warning failed")
+	}
+}
+
+// scheduledRestart 通过 stop 命令优雅关闭服务器后重新启动，服务器未运行时不做任何事
+func (server *Server) scheduledRestart() {
+	logger := log.WithField("server_id", server.id)
+	if !server.Active() {
+		logger.Info("server is not running, skipping scheduled restart")
+		return
+	}
+	logger.Info("performing scheduled restart")
+	if _, err := server.Shutdown(); err != nil {
+		logger.WithError(err).Error("scheduled restart: stop server failed")
+		return
+	}
+	if err := server.Start(); err != nil {
+		logger.WithError(err).Error("scheduled restart: start server failed")
+	}
+}
+
+// sleepUntil 等待到 t，stop 被关闭时返回 false
+func sleepUntil(t time.Time, stop chan struct{}) bool {
+	timer := time.NewTimer(time.Until(t))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-stop:
+		return false
+	}
+}
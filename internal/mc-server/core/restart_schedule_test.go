@@ -0,0 +1,152 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRestartSchedule_Validate(t *testing.T) {
+	schedule := RestartSchedule{Enabled: true, Cron: "0 4 * * *"}
+	if err := schedule.Validate(); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if len(schedule.Warnings) != 4 || schedule.BusyAction != BusyPostpone || schedule.PostponeMinutes != 5 {
+		t.Fatalf("unexpected defaults: %+v", schedule)
+	}
+	schedule = RestartSchedule{Cron: "0 4 * * *", Warnings: []int{10, 600, 60}}
+	if err := schedule.Validate(); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if schedule.Warnings[0] != 600 || schedule.Warnings[2] != 10 {
+		t.Fatalf("warnings should be sorted from earliest: %v", schedule.Warnings)
+	}
+	for _, invalid := range []RestartSchedule{
+		{Enabled: true},
+		{Enabled: true, Cron: "0 4 * *"},
+		{Enabled: true, Cron: "0 4 * * *", Warnings: []int{0}},
+		{Enabled: true, Cron: "0 4 * * *", BusyAction: "wait"},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("expected %+v to be rejected", invalid)
+		}
+	}
+}
+
+func TestFormatCountdown(t *testing.T) {
+	cases := map[int]string{600: "10 minutes", 60: "1 minute", 90: "90 seconds", 1: "1 second", 10: "10 seconds"}
+	for seconds, expected := range cases {
+		if actual := formatCountdown(seconds); actual != expected {
+			t.Errorf("formatCountdown(%d) = %q, expected %q", seconds, actual, expected)
+		}
+	}
+}
+
+func TestServer_SetRestartSchedule(t *testing.T) {
+	server := testFakeServer(t)
+	schedule, err := server.SetRestartSchedule(RestartSchedule{Enabled: true, Cron: "0 4 * * *"})
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	t.Cleanup(func() {
+		server.scheduleRestarts(RestartSchedule{})
+	})
+	waitFor(t, time.Second, func() bool {
+		return !server.NextScheduledRestart().IsZero()
+	})
+	if next := server.NextScheduledRestart(); next.Hour() != 4 || next.Minute() != 0 {
+		t.Fatalf("unexpected next restart: %v", next)
+	}
+	loaded, err := server.RestartSchedule()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if loaded.Cron != schedule.Cron || !loaded.Enabled || len(loaded.Warnings) != len(DefaultRestartWarnings) {
+		t.Fatalf("unexpected persisted schedule: %+v", loaded)
+	}
+
+	_, err = server.SetRestartSchedule(RestartSchedule{Cron: "0 4 * * *"})
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if !server.NextScheduledRestart().IsZero() {
+		t.Fatal("disabled schedule should have no next restart")
+	}
+}
+
+func TestServer_CountdownAndRestart(t *testing.T) {
+	server := testFakeServer(t)
+	err := server.Start()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	_, err = server.process.WaitReady(5 * time.Second)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	before := server.Status().Since
+	schedule := RestartSchedule{Enabled: true, Cron: "* * * * *", Warnings: []int{1}}
+	if err = schedule.Validate(); err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	// 正在备份且配置为跳过时不重启
+	server.backingUp.Store(true)
+	if !server.countdownAndRestart(RestartSchedule{Warnings: []int{}, BusyAction: BusySkip}, time.Now().Add(100*time.Millisecond), make(chan struct{})) {
+		t.Fatal("countdown should not be cancelled")
+	}
+	if !server.Status().Since.Equal(before) {
+		t.Fatal("busy server should not be restarted")
+	}
+	server.backingUp.Store(false)
+
+	// 被复制、升级或回滚时同样视为正忙
+	server.cloning.Add(1)
+	if task, busy := server.Busy(); !busy || task != "clone" {
+		t.Fatalf("cloning server should be busy, got %q", task)
+	}
+	server.cloning.Add(-1)
+	if err = server.beginUpgrade("1.21.50.07"); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if task, busy := server.Busy(); !busy || task != "upgrade" {
+		t.Fatalf("upgrading server should be busy, got %q", task)
+	}
+	server.finishUpgrade(nil, nil)
+	if err = server.beginRollback("1.21.44.01"); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if task, busy := server.Busy(); !busy || task != "rollback" {
+		t.Fatalf("rolling back server should be busy, got %q", task)
+	}
+	server.finishRollback()
+	if _, busy := server.Busy(); busy {
+		t.Fatal("server should no longer be busy")
+	}
+
+	if !server.countdownAndRestart(schedule, time.Now().Add(1500*time.Millisecond), make(chan struct{})) {
+		t.Fatal("countdown should not be cancelled")
+	}
+	found := false
+	for _, line := range server.Console().Lines(0) {
+		if line.Text == "[INFO] say Server will restart in 1 second" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("expected countdown warning")
+	}
+	_, err = server.process.WaitReady(5 * time.Second)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if !server.Status().Since.After(before) {
+		t.Fatal("expected server to be restarted")
+	}
+
+	// 取消调度时立即返回
+	stop := make(chan struct{})
+	close(stop)
+	if server.countdownAndRestart(schedule, time.Now().Add(time.Hour), stop) {
+		t.Fatal("expected cancelled countdown")
+	}
+}
@@ -0,0 +1,215 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/candbright/go-log/log"
+	"github.com/pkg/errors"
+)
+
+// InstalledVersion 服务器根目录下已解压的版本
+type InstalledVersion struct {
+	Version     string    `json:"version"`
+	Current     bool      `json:"current"`
+	InstalledAt time.Time `json:"installed_at"`
+}
+
+// RollbackResult 回滚的结果，Backup 为回滚前当前世界数据的安全备份目录
+type RollbackResult struct {
+	FromVersion string `json:"from_version"`
+	ToVersion   string `json:"to_version"`
+	Backup      string `json:"backup"`
+}
+
+// RollbackBackupsDir 存放回滚前安全备份的目录
+func (server *Server) RollbackBackupsDir() string {
+	return path.Join(server.rootDir, "rollback_backups")
+}
+
+// InstalledVersions 列出根目录下包含服务器可执行文件的版本目录，按安装时间从新到旧排序
+func (server *Server) InstalledVersions() ([]InstalledVersion, error) {
+	entries, err := os.ReadDir(server.rootDir)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	current := server.GetVersion()
+	versions := make([]InstalledVersion, 0)
+	for _, entry := range entries {
+		if !entry.IsDir() || !versionInstalled(path.Join(server.rootDir, entry.Name())) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		versions = append(versions, InstalledVersion{
+			Version:     entry.Name(),
+			Current:     entry.Name() == current,
+			InstalledAt: info.ModTime(),
+		})
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].InstalledAt.After(versions[j].InstalledAt)
+	})
+	return versions, nil
+}
+
+// versionInstalled 判断目录中是否有服务器可执行文件
+func versionInstalled(dir string) bool {
+	return Exists(path.Join(dir, "bedrock_server")) || Exists(path.Join(dir, "bedrock_server.exe"))
+}
+
+// Rollback 将服务器切换回已安装的旧版本。先把当前的世界、白名单和权限备份到 RollbackBackupsDir，
+// 再复制到目标版本目录并切换 version 文件，原本运行中的服务器会重新启动。
+// 世界已被比目标版本更新的游戏打开过时拒绝回滚，force 为 true 时跳过该检查。
+func (server *Server) Rollback(version string, force bool) (RollbackResult, error) {
+	from := server.GetVersion()
+	if version == from {
+		return RollbackResult{}, errors.Errorf("server [%s] is already on version [%s]", server.id, version)
+	}
+	if !versionInstalled(path.Join(server.rootDir, version)) || strings.ContainsAny(version, `/\`) {
+		return RollbackResult{}, errors.Errorf("version [%s] is not installed", version)
+	}
+	if server.Downloading() {
+		return RollbackResult{}, errors.New("server is downloading")
+	}
+	// 与升级共用同一个守卫，拒绝同时进行的升级或回滚
+	if err := server.beginRollback(version); err != nil {
+		return RollbackResult{}, err
+	}
+	defer server.finishRollback()
+	if !force {
+		if err := server.checkWorldsOpenable(version); err != nil {
+			return RollbackResult{}, err
+		}
+	}
+
+	logger := log.WithField("server_id", server.id).WithField("version", version)
+	wasRunning := server.Active()
+	if wasRunning {
+		if _, err := server.Shutdown(); err != nil {
+			return RollbackResult{}, err
+		}
+	}
+	result, err := server.rollback(from, version)
+	if err != nil {
+		logger.WithError(err).Error("rollback server failed")
+		if wasRunning {
+			if startErr := server.Start(); startErr != nil {
+				logger.WithError(startErr).Error("restart server after failed rollback failed")
+			}
+		}
+		return RollbackResult{}, err
+	}
+	logger.WithField("backup", result.Backup).Info("server rolled back")
+	if wasRunning {
+		return result, server.Start()
+	}
+	return result, nil
+}
+
+func (server *Server) rollback(from, version string) (RollbackResult, error) {
+	oldDir := server.WorkDir()
+	newDir := path.Join(server.rootDir, version)
+	backup := path.Join(server.RollbackBackupsDir(), fmt.Sprintf("%s-%s", from, time.Now().Format("20060102-150405")))
+	err := os.MkdirAll(backup, os.ModePerm)
+	if err != nil {
+		return RollbackResult{}, errors.WithStack(err)
+	}
+	for _, name := range carriedFiles {
+		if err = copyIfExists(path.Join(oldDir, name), path.Join(backup, name)); err != nil {
+			return RollbackResult{}, err
+		}
+	}
+	// 复制期间加锁，防止服务器被启动
+	err = os.WriteFile(server.DownloadingFilePath(), nil, 0666)
+	if err != nil {
+		return RollbackResult{}, errors.WithStack(err)
+	}
+	_, err = carryOver(oldDir, newDir)
+	_ = os.Remove(server.DownloadingFilePath())
+	if err != nil {
+		return RollbackResult{}, err
+	}
+	if err = server.switchVersion(version); err != nil {
+		return RollbackResult{}, err
+	}
+	return RollbackResult{FromVersion: from, ToVersion: version, Backup: backup}, nil
+}
+
+// checkWorldsOpenable 检查当前所有世界最近一次打开的游戏版本是否不高于 version
+func (server *Server) checkWorldsOpenable(version string) error {
+	target, ok := parseVersion(version)
+	if !ok {
+		return errors.Errorf("cannot compare world versions with [%s]", version)
+	}
+	entries, err := os.ReadDir(server.WorldsDir())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for _, entry := range entries {
+		levelDat := path.Join(server.WorldsDir(), entry.Name(), "level.dat")
+		if !entry.IsDir() || !Exists(levelDat) {
+			continue
+		}
+		opened, err := ReadLastOpenedVersion(levelDat)
+		if err != nil {
+			return err
+		}
+		if compareVersions(opened, target) > 0 {
+			return errors.Errorf("world [%s] was opened with newer version [%s]", entry.Name(), formatVersion(opened))
+		}
+	}
+	return nil
+}
+
+// parseVersion 解析形如 1.21.44.01 的版本号
+func parseVersion(version string) ([]int, bool) {
+	parts := strings.Split(version, ".")
+	values := make([]int, 0, len(parts))
+	for _, part := range parts {
+		v, err := strconv.Atoi(part)
+		if err != nil || v < 0 {
+			return nil, false
+		}
+		values = append(values, v)
+	}
+	return values, true
+}
+
+// compareVersions 比较前四段版本号，lastOpenedWithVersion 的第五段为预览版标记，不参与比较
+func compareVersions(a, b []int) int {
+	for i := 0; i < 4; i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func formatVersion(version []int) string {
+	parts := make([]string, 0, len(version))
+	for _, v := range version {
+		parts = append(parts, strconv.Itoa(v))
+	}
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,110 @@
+package core
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestServer_Rollback(t *testing.T) {
+	server := testFakeServer(t)
+	// 当前版本 fake 之外再安装一个旧版本
+	oldDir := path.Join(server.rootDir, "1.21.44.01")
+	writeFakeServer(t, oldDir, fakeServerScript)
+	if err := os.WriteFile(path.Join(oldDir, "allowlist.json"), []byte(`[]`), 0666); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	example, err := os.ReadFile("example/server.properties")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	for _, dir := range []string{oldDir, server.WorkDir()} {
+		if err = os.WriteFile(path.Join(dir, "server.properties"), example, 0666); err != nil {
+			t.Fatalf("%+v", err)
+		}
+	}
+	if err = os.WriteFile(path.Join(server.WorkDir(), "allowlist.json"), []byte(`[{"name":"Steve"}]`), 0666); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	levelDat := path.Join(server.WorldsDir(), "Bedrock level", "level.dat")
+	writeLevelDat(t, levelDat, []int{1, 21, 50, 7, 0})
+
+	versions, err := server.InstalledVersions()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("unexpected versions: %+v", versions)
+	}
+	for _, v := range versions {
+		if v.Current != (v.Version == "fake") {
+			t.Fatalf("unexpected versions: %+v", versions)
+		}
+	}
+
+	if _, err = server.Rollback("fake", false); err == nil {
+		t.Fatal("rolling back to the current version should be rejected")
+	}
+	if _, err = server.Rollback("1.0.0", false); err == nil {
+		t.Fatal("rolling back to a missing version should be rejected")
+	}
+	if _, err = server.Rollback("1.21.44.01", false); err == nil {
+		t.Fatal("world opened by a newer version should be rejected")
+	}
+
+	writeLevelDat(t, levelDat, []int{1, 21, 44, 1, 0})
+	// 升级或回滚进行中时拒绝另一个回滚或升级
+	if err = server.beginUpgrade("1.21.50.07"); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if _, err = server.Rollback("1.21.44.01", false); err == nil {
+		t.Fatal("rollback during an upgrade should be rejected")
+	}
+	server.finishUpgrade(nil, errors.New("cancelled"))
+	if err = server.beginRollback("1.21.44.01"); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if _, err = server.Rollback("1.21.44.01", false); err == nil {
+		t.Fatal("concurrent rollback should be rejected")
+	}
+	if err = server.beginUpgrade("1.21.50.07"); err == nil {
+		t.Fatal("upgrade during a rollback should be rejected")
+	}
+	server.finishRollback()
+
+	if err = server.Start(); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if _, err = server.process.WaitReady(5 * time.Second); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	result, err := server.Rollback("1.21.44.01", false)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if result.FromVersion != "fake" || server.GetVersion() != "1.21.44.01" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	for _, file := range []string{
+		path.Join(result.Backup, "allowlist.json"),
+		path.Join(result.Backup, "worlds", "Bedrock level", "level.dat"),
+		path.Join(server.WorldsDir(), "Bedrock level", "level.dat"),
+	} {
+		if !Exists(file) {
+			t.Errorf("expected %s", file)
+		}
+	}
+	data, _ := os.ReadFile(path.Join(server.WorkDir(), "allowlist.json"))
+	if string(data) != `[{"name":"Steve"}]` {
+		t.Fatalf("allowlist not carried over: %s", data)
+	}
+	if !server.Active() {
+		t.Fatal("server should be restarted after rollback")
+	}
+	if _, err = server.process.WaitReady(5 * time.Second); err != nil {
+		t.Fatalf("%+v", err)
+	}
+}
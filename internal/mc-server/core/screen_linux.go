@@ -1,9 +1,21 @@
 package core
 
 import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
 	"github.com/pkg/errors"
 )
 
+// screenPidTimeout 启动后等待服务器写入 pid 文件的时间
+const screenPidTimeout = 5 * time.Second
+
 type Screen struct {
 	Name string
 }
@@ -12,8 +24,15 @@ func (s Screen) Create() error {
 	return errors.WithStack(Command("screen", "-dmS", s.Name).Run())
 }
 
+// CreateIn 在 dir 目录下创建会话并直接运行 shell 命令，命令结束后会话随之结束
+func (s Screen) CreateIn(dir string, script string) error {
+	cmd := Command("screen", "-dmS", s.Name, "sh", "-c", script)
+	cmd.Dir = dir
+	return errors.WithStack(cmd.Run())
+}
+
 func (s Screen) Exists() bool {
-	err := Command("screen", "-ls", s.Name).Run()
+	err := Command("screen", "-S", s.Name, "-Q", "select", ".").Run()
 	return err == nil
 }
 
@@ -25,3 +44,162 @@ func (s Screen) ExecCmd(arg ...string) error {
 	arg = append([]string{"-X", s.Name}, arg...)
 	return errors.WithStack(Command("screen", arg...).Run())
 }
+
+// Stuff 向会话窗口输入一行文本
+func (s Screen) Stuff(line string) error {
+	return errors.WithStack(Command("screen", "-S", s.Name, "-p", "0", "-X", "stuff", line+"\n").Run())
+}
+
+// screenSession 将 Screen 适配为进程会话后端。
+// 服务器的输出重定向到日志文件后逐行读取，启动时写入 pid 文件以便识别进程和发送信号
+type screenSession struct {
+	Screen
+	dir      string
+	execFile string
+
+	mu        sync.Mutex
+	pid       int
+	procStart uint64
+	startedAt time.Time
+	handlers  []func(line string)
+}
+
+func (s *screenSession) LogFile() string {
+	return path.Join(s.dir, s.Name+".log")
+}
+
+func (s *screenSession) PidFile() string {
+	return path.Join(s.dir, s.Name+".pid")
+}
+
+func (s *screenSession) Start() error {
+	_ = os.Remove(s.PidFile())
+	// 日志以追加方式写入，轮转截断后才能从头继续写，先删除上次运行的日志
+	_ = os.Remove(s.LogFile())
+	// 先写入 shell 自身的 pid，再 exec 替换为服务器，pid 文件中即为服务器进程号
+	script := fmt.Sprintf("echo $$ > '%s'; exec env LD_LIBRARY_PATH=. '%s' >> '%s' 2>&1",
+		s.PidFile(), s.execFile, s.LogFile())
+	err := s.CreateIn(s.dir, script)
+	if err != nil {
+		return err
+	}
+	pid, err := s.waitPid()
+	if err != nil {
+		_ = s.Exit()
+		return err
+	}
+	procStart, _ := procStartTime(pid)
+	s.mu.Lock()
+	s.pid = pid
+	s.procStart = procStart
+	s.startedAt = time.Now()
+	s.mu.Unlock()
+	go s.follow(0)
+	return nil
+}
+
+func (s *screenSession) waitPid() (int, error) {
+	deadline := time.Now().Add(screenPidTimeout)
+	for {
+		data, err := os.ReadFile(s.PidFile())
+		if err == nil {
+			if pid, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil {
+				return pid, nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return 0, errors.Errorf("screen session %s did not start in time", s.Name)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func (s *screenSession) State() SessionState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return SessionState{
+		Backend:   BackendScreen,
+		Session:   s.Name,
+		Pid:       s.pid,
+		ProcStart: s.procStart,
+		StartedAt: s.startedAt,
+	}
+}
+
+// Attach screen 会话在面板退出后依然存在，会话名与进程均一致即可接管，从日志文件末尾继续读取输出
+func (s *screenSession) Attach(state SessionState) bool {
+	if state.Session != s.Name || state.Pid <= 0 {
+		return false
+	}
+	if procStart, ok := procStartTime(state.Pid); !ok || procStart != state.ProcStart {
+		return false
+	}
+	s.mu.Lock()
+	s.pid = state.Pid
+	s.procStart = state.ProcStart
+	s.startedAt = state.StartedAt
+	s.mu.Unlock()
+	go s.follow(fileSize(s.LogFile()))
+	return true
+}
+
+// Active 通过 pid 与进程启动时间判断服务器是否仍在运行，无需调用 screen 命令
+func (s *screenSession) Active() bool {
+	s.mu.Lock()
+	pid, procStart := s.pid, s.procStart
+	s.mu.Unlock()
+	if pid <= 0 {
+		return false
+	}
+	current, ok := procStartTime(pid)
+	return ok && current == procStart
+}
+
+func (s *screenSession) Send(line string) error {
+	return s.Stuff(line)
+}
+
+func (s *screenSession) Terminate() error {
+	return s.signal(syscall.SIGTERM)
+}
+
+func (s *screenSession) Kill() error {
+	err := s.signal(syscall.SIGKILL)
+	if s.Exists() {
+		_ = s.Exit()
+	}
+	return err
+}
+
+func (s *screenSession) signal(sig syscall.Signal) error {
+	if !s.Active() {
+		return errors.New("server process is not running")
+	}
+	s.mu.Lock()
+	pid := s.pid
+	s.mu.Unlock()
+	return errors.WithStack(syscall.Kill(pid, sig))
+}
+
+func (s *screenSession) OnOutput(handler func(line string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers = append(s.handlers, handler)
+}
+
+func (s *screenSession) emit(line string) {
+	line = strings.TrimRight(line, "\r\n")
+	s.mu.Lock()
+	handlers := s.handlers
+	s.mu.Unlock()
+	for _, handler := range handlers {
+		handler(line)
+	}
+}
+
+// follow 从 offset 处持续读取日志文件直到服务器退出
+func (s *screenSession) follow(offset int64) {
+	followFile(s.LogFile(), offset, maxConsoleLogSize, func() bool {
+		return !s.Active()
+	}, s.emit)
+}
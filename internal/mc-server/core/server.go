@@ -6,10 +6,11 @@ import (
 	"path"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/candbright/go-log/log"
-	"github.com/candbright/go-server/internal/mc-server/core/model"
 	"github.com/candbright/go-server/pkg/config"
 	"github.com/candbright/go-server/pkg/dw"
 	"github.com/pkg/errors"
@@ -18,6 +19,12 @@ import (
 type ServerConfig struct {
 	ID      string
 	RootDir string
+	// StopTimeout 关闭服务器时等待 stop 命令生效的时间
+	StopTimeout time.Duration
+	// Backend 全局配置的进程会话后端，服务器目录下的 backend 文件可单独覆盖
+	Backend string
+	// History 记录玩家进出的存储，为空时不记录
+	History *PlayerHistory
 }
 
 type Server struct {
@@ -25,22 +32,199 @@ type Server struct {
 	name             string
 	version          string
 	rootDir          string
+	stopTimeout      time.Duration
+	defaultBackend   string
 	process          *Process
+	console          *Console
+	players          *PlayerTracker
+	bans             *BanList
+	announcements    *AnnouncementList
+	crash            *CrashMonitor
+	restartTimer     *time.Timer
+	restartStop      chan struct{}
+	nextRestart      time.Time
+	createErr        string
+	backingUp        atomic.Bool
+	cloning          atomic.Int32
+	upgrade          upgradeTracker
+	mu               sync.Mutex
+	versionMu        sync.Mutex
 	backup           bool
 	serverProperties *ServerProperties
 }
 
 func NewServer(cfg ServerConfig) (*Server, error) {
 	server := &Server{
-		id:      cfg.ID,
-		rootDir: cfg.RootDir,
+		id:             cfg.ID,
+		rootDir:        cfg.RootDir,
+		stopTimeout:    cfg.StopTimeout,
+		defaultBackend: cfg.Backend,
+		console:        NewConsole(DefaultConsoleSize),
+		players:        NewPlayerTracker(),
+	}
+	server.console.OnWrite(server.players.Observe)
+	if cfg.History != nil {
+		server.players.OnEvent(func(event PlayerEvent) {
+			cfg.History.Record(server.id, event)
+		})
+	}
+	crash, err := NewCrashMonitor(server.RestartPolicyFilePath(), server.CrashesFilePath())
+	if err != nil {
+		return nil, err
+	}
+	server.crash = crash
+	bans, err := NewBanList(server.BanFilePath())
+	if err != nil {
+		return nil, err
+	}
+	server.bans = bans
+	announcements, err := NewAnnouncementList(server.AnnouncementsFilePath())
+	if err != nil {
+		return nil, err
+	}
+	server.announcements = announcements
+	if schedule, err := server.RestartSchedule(); err != nil {
+		log.WithError(err).WithField("server_id", server.id).Error("load restart schedule failed")
+	} else {
+		server.scheduleRestarts(schedule)
+	}
+	server.players.OnEvent(server.enforceBan)
+	server.process = server.newProcess()
+	// 面板重启后重新接管仍在运行的服务器进程
+	if server.process.Attach() {
+		log.WithField("server_id", server.id).Info("reattached to running server process")
 	}
-	server.process = NewProcess(ProcessConfig{
-		RootDir: server.WorkDir(),
-	})
 	return server, nil
 }
 
+func (server *Server) newProcess() *Process {
+	p := NewProcess(ProcessConfig{
+		RootDir:     server.WorkDir(),
+		Backend:     server.Backend(),
+		Console:     server.console,
+		StopTimeout: server.stopTimeout,
+		StateFile:   server.ProcessStateFilePath(),
+	})
+	p.OnExit(func(exit ProcessExit) {
+		server.handleExit(p, exit)
+	})
+	return p
+}
+
+// handleExit 处理进程退出：非面板发起的退出记为崩溃，并按重启策略安排重启
+func (server *Server) handleExit(p *Process, exit ProcessExit) {
+	server.players.Reset(exit.Time)
+	if exit.Requested {
+		return
+	}
+	lastLines := make([]string, 0, crashLastLines)
+	for _, line := range server.console.Lines(crashLastLines) {
+		lastLines = append(lastLines, line.Text)
+	}
+	restart, delay := server.crash.Record(exit, lastLines)
+	logger := log.WithField("server_id", server.id).WithField("exit_code", exit.ExitCode)
+	if !restart {
+		logger.Warn("server process exited unexpectedly, not restarting")
+		return
+	}
+	logger.Warnf("server process exited unexpectedly, restarting in %v", delay)
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.cancelRestartLocked()
+	server.restartTimer = time.AfterFunc(delay, func() {
+		server.mu.Lock()
+		server.restartTimer = nil
+		current := server.process
+		server.mu.Unlock()
+		// 进程已被重建（如 Reload）或已被手动启动时不再重启
+		if current != p || p.Active() {
+			return
+		}
+		if err := p.Start(); err != nil {
+			logger.WithError(err).Error("restart server process failed")
+		}
+	})
+}
+
+func (server *Server) cancelRestartLocked() {
+	if server.restartTimer != nil {
+		server.restartTimer.Stop()
+		server.restartTimer = nil
+	}
+}
+
+// cancelRestart 取消尚未执行的自动重启，手动启停服务器时调用
+func (server *Server) cancelRestart() {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.cancelRestartLocked()
+}
+
+func (server *Server) RestartPolicy() RestartPolicy {
+	return server.crash.Policy()
+}
+
+func (server *Server) SetRestartPolicy(policy RestartPolicy) error {
+	return server.crash.SetPolicy(policy)
+}
+
+func (server *Server) CrashEvents() []CrashEvent {
+	return server.crash.Events()
+}
+
+func (server *Server) CrashStats() CrashStats {
+	return server.crash.Stats()
+}
+
+// Backend 返回服务器配置的进程会话后端：优先使用服务器单独设置的后端，其次为全局配置
+func (server *Server) Backend() string {
+	bytes, err := os.ReadFile(server.BackendFilePath())
+	if err == nil {
+		if backend := strings.TrimSpace(string(bytes)); backend != "" {
+			return backend
+		}
+	}
+	if server.defaultBackend != "" {
+		return server.defaultBackend
+	}
+	return DefaultBackend
+}
+
+// ProcessBackend 返回当前进程实际使用的会话后端，接管的进程沿用其启动时的后端
+func (server *Server) ProcessBackend() string {
+	return server.currentProcess().Backend()
+}
+
+// SetBackend 单独设置服务器的进程会话后端，为空时恢复使用全局配置。服务器运行时不允许修改
+func (server *Server) SetBackend(backend string) error {
+	if backend != "" {
+		if err := ValidateBackend(backend); err != nil {
+			return err
+		}
+	}
+	if server.currentProcess().Active() {
+		return errors.New("cannot change backend while server is running")
+	}
+	var err error
+	if backend == "" {
+		err = os.Remove(server.BackendFilePath())
+		if os.IsNotExist(err) {
+			err = nil
+		}
+	} else {
+		err = os.WriteFile(server.BackendFilePath(), []byte(backend), 0666)
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	server.cancelRestart()
+	process := server.newProcess()
+	server.mu.Lock()
+	server.process = process
+	server.mu.Unlock()
+	return nil
+}
+
 func (server *Server) BackupDir() string {
 	return path.Join(config.Global.Get("mc.path"), "backup", fmt.Sprintf("backup-"+server.rootDir))
 }
@@ -53,6 +237,22 @@ func (server *Server) DownloadingFilePath() string {
 	return path.Join(server.rootDir, "downloading")
 }
 
+func (server *Server) RestartPolicyFilePath() string {
+	return path.Join(server.rootDir, "restart_policy.json")
+}
+
+func (server *Server) CrashesFilePath() string {
+	return path.Join(server.rootDir, "crashes.json")
+}
+
+func (server *Server) BackendFilePath() string {
+	return path.Join(server.rootDir, "backend")
+}
+
+func (server *Server) ProcessStateFilePath() string {
+	return path.Join(server.rootDir, "process.json")
+}
+
 func (server *Server) WorkDir() string {
 	return path.Join(server.rootDir, server.GetVersion())
 }
@@ -70,38 +270,95 @@ func (server *Server) AllowListFilePath() string {
 	return path.Join(server.WorkDir(), "allowlist.json")
 }
 
+// MaxServerNameLength 服务器名称的最大长度
+const MaxServerNameLength = 128
+
+func (server *Server) ServerNameFilePath() string {
+	return path.Join(server.rootDir, "servername")
+}
+
 func (server *Server) GetServerName() string {
 	if server.name != "" {
 		return server.name
 	}
-	bytes, err := os.ReadFile(path.Join(server.rootDir, "servername"))
-	if err != nil {
-		server.name = "unnamed server"
+	bytes, err := os.ReadFile(server.ServerNameFilePath())
+	if err != nil || len(bytes) == 0 {
+		return "unnamed server"
 	}
-	if len(bytes) > 128 {
-		bytes = bytes[:128]
+	if len(bytes) > MaxServerNameLength {
+		bytes = bytes[:MaxServerNameLength]
 	}
 	server.name = string(bytes)
 	return server.name
 }
 
+// SetServerName 修改面板中显示的服务器名称，写入服务器目录下的 servername 文件
+func (server *Server) SetServerName(name string) error {
+	name, err := validServerName(name)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(server.ServerNameFilePath(), []byte(name), 0666)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	server.name = name
+	return nil
+}
+
+func validServerName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("server name is required")
+	}
+	if len(name) > MaxServerNameLength || strings.ContainsAny(name, "\r\n") {
+		return "", errors.Errorf("server name must be a single line of at most %d bytes", MaxServerNameLength)
+	}
+	return name, nil
+}
+
 func (server *Server) GetID() string {
 	return server.id
 }
 
+// OnlinePlayers 返回根据服务器日志统计的在线玩家
+func (server *Server) OnlinePlayers() []OnlinePlayer {
+	return server.players.Online()
+}
+
+// Console 返回服务器控制台输出缓冲，进程重建后仍保持不变
+func (server *Server) Console() *Console {
+	return server.console
+}
+
 func (server *Server) GetVersion() string {
+	server.versionMu.Lock()
+	defer server.versionMu.Unlock()
 	if server.version != "" {
 		return server.version
 	}
 	bytes, err := os.ReadFile(server.VersionFilePath())
 	if err != nil {
-		server.version = ""
-		return server.version
+		return ""
 	}
 	server.version = string(bytes)
 	return server.version
 }
 
+// setVersion 更新缓存的版本号，版本文件由调用方写入
+func (server *Server) setVersion(version string) {
+	server.versionMu.Lock()
+	defer server.versionMu.Unlock()
+	server.version = version
+}
+
+// currentProcess 返回当前的进程，进程会在 SetBackend、Reload 时被替换
+func (server *Server) currentProcess() *Process {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	return server.process
+}
+
 func (server *Server) ServerExist() bool {
 	//是否正在下载
 	downloading := server.Downloading()
@@ -126,7 +383,7 @@ func (server *Server) Active() bool {
 	if !exist {
 		return false
 	}
-	return server.process.Active()
+	return server.currentProcess().Active()
 }
 
 func (server *Server) ServerProperties() (*ServerProperties, error) {
@@ -136,7 +393,7 @@ func (server *Server) ServerProperties() (*ServerProperties, error) {
 	}
 	if server.serverProperties == nil {
 		server.serverProperties = NewServerProperties(ServerPropertiesConfig{
-			Version: server.version,
+			Version: server.GetVersion(),
 			RootDir: server.WorkDir(),
 		})
 	}
@@ -144,57 +401,83 @@ func (server *Server) ServerProperties() (*ServerProperties, error) {
 }
 
 func (server *Server) Start() error {
-	return server.process.Start()
+	server.cancelRestart()
+	return server.currentProcess().Start()
+}
+
+// StartAndWait 启动服务器并等待其完成启动，详见 Process.WaitReady
+func (server *Server) StartAndWait(timeout time.Duration) (ProcessStatus, error) {
+	err := server.Start()
+	if err != nil {
+		return server.Status(), err
+	}
+	return server.currentProcess().WaitReady(timeout)
+}
+
+// Status 返回服务器的运行状态：starting、running、failed 或 stopped
+func (server *Server) Status() ProcessStatus {
+	return server.currentProcess().Status()
 }
 
 func (server *Server) Stop() error {
-	return server.process.Stop()
+	server.cancelRestart()
+	return server.currentProcess().Stop()
+}
+
+// Shutdown 优雅关闭服务器并返回关闭过程
+func (server *Server) Shutdown() (StopResult, error) {
+	server.cancelRestart()
+	return server.currentProcess().Shutdown()
 }
 
 func (server *Server) Reload() error {
 	needStart := false
 	if server.Active() {
-		err := server.process.Stop()
+		err := server.currentProcess().Stop()
 		if err != nil {
 			return err
 		}
 		needStart = true
 	}
-	server.process = NewProcess(ProcessConfig{
-		RootDir: server.WorkDir(),
-	})
+	server.cancelRestart()
+	process := server.newProcess()
+	server.mu.Lock()
+	server.process = process
+	server.mu.Unlock()
 	if needStart {
-		err := server.process.Start()
+		err := process.Start()
 		if err != nil {
 			return err
 		}
 	}
 	server.serverProperties = NewServerProperties(ServerPropertiesConfig{
-		Version: server.version,
+		Version: server.GetVersion(),
 		RootDir: server.WorkDir(),
 	})
 
 	return nil
 }
 
+// Delete 关闭服务器并删除整个服务器目录，包括所有版本、配置和记录
 func (server *Server) Delete() error {
+	if server.Downloading() {
+		return errors.New("server is downloading")
+	}
+	server.scheduleRestarts(RestartSchedule{})
 	//如果服务器正在运行则先关闭
-	active := server.process.Active()
-	if active {
-		err := server.process.Stop()
+	if server.currentProcess().Active() {
+		_, err := server.Shutdown()
 		if err != nil {
 			return err
 		}
 	}
+	server.stopBackup()
 	//TODO: 备份
 
 	//删除服务器目录
-	existS := server.ServerExist()
-	if existS {
-		err := os.RemoveAll(server.WorkDir())
-		if err != nil {
-			return err
-		}
+	err := os.RemoveAll(server.rootDir)
+	if err != nil {
+		return errors.WithStack(err)
 	}
 	return nil
 }
@@ -226,6 +509,8 @@ func (server *Server) backupTick() {
 	if !server.Active() {
 		return
 	}
+	server.backingUp.Store(true)
+	defer server.backingUp.Store(false)
 	//zip data
 	sourceDir := server.WorldsDir()
 	backupDir := server.BackupDir()
@@ -272,28 +557,15 @@ func (server *Server) backupClearTick() {
 	}
 }
 
-func (server *Server) AllowListAdd(username string) error {
-	return server.process.ExecCmd("allowlist", "add", username)
-}
-
-func (server *Server) AllowListDelete(username string) error {
-	return server.process.ExecCmd("allowlist", "remove", username)
-}
-
-func (server *Server) AllowListOn() error {
-	return server.process.ExecCmd("allowlist", "on")
+// ExecCmd 执行任意控制台命令，返回 window 时间窗口内服务器的输出
+func (server *Server) ExecCmd(window time.Duration, command string) ([]string, error) {
+	return server.currentProcess().ExecCmdOutput(window, command)
 }
 
-func (server *Server) AllowListOff() error {
-	return server.process.ExecCmd("allowlist", "off")
-}
-
-func (server *Server) GetAllowList() (model.AllowList, error) {
-	w, err := dw.Default[model.AllowList](server.AllowListFilePath())
-	if err != nil {
-		return nil, err
-	}
-	return w.Data, nil
+// execCmd 执行命令并丢弃输出，服务器拒绝执行时返回 CommandError
+func (server *Server) execCmd(arg ...string) error {
+	_, err := server.currentProcess().ExecCmdOutput(DefaultCommandWindow, arg...)
+	return err
 }
 
 // ApplySave 将存档应用到服务器
@@ -330,3 +602,41 @@ func (server *Server) ApplySave(savePath string) error {
 
 	return nil
 }
+
+// initialPropertiesFile 创建服务器时指定、下载完成后再写入 server.properties 的初始配置
+const initialPropertiesFile = "initial_properties.json"
+
+// applyInitialProperties 将创建服务器时指定的初始配置写入 server.properties。
+// 无论成功与否都删除记录，失败时返回错误，由调用方记录到 CreateError
+func (server *Server) applyInitialProperties() error {
+	file := path.Join(server.rootDir, initialPropertiesFile)
+	if !Exists(file) {
+		return nil
+	}
+	defer os.Remove(file)
+	w, err := dw.JsonOrDefault[map[string]string](file, nil)
+	if err != nil {
+		return errors.WithMessage(err, "load initial server properties")
+	}
+	sp, err := server.ServerProperties()
+	if err != nil {
+		return errors.WithMessage(err, "apply initial server properties")
+	}
+	return errors.WithMessage(sp.SetAll(w.Data), "apply initial server properties")
+}
+
+// CreateError 返回创建服务器时后台下载或应用初始配置失败的原因，没有失败时为空
+func (server *Server) CreateError() string {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	return server.createErr
+}
+
+func (server *Server) setCreateError(err error) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.createErr = ""
+	if err != nil {
+		server.createErr = err.Error()
+	}
+}
@@ -1,17 +1,21 @@
 package core
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/candbright/go-log/log"
 	"github.com/candbright/go-server/pkg/downloader"
+	"github.com/candbright/go-server/pkg/dw"
+	"github.com/pkg/errors"
 )
 
 type SaveInfo struct {
@@ -25,6 +29,12 @@ type ServerManagerConfig struct {
 	RootDir      string
 	LoadInterval time.Duration
 	CacheTTL     time.Duration
+	// StopTimeout 关闭服务器时等待 stop 命令生效的时间
+	StopTimeout time.Duration
+	// Backend 全局的进程会话后端，对应配置项 mc.process.backend
+	Backend string
+	// Catalog 可用版本目录，为空时使用 Mojang 官方的下载链接接口
+	Catalog *VersionCatalog
 }
 
 type ServerManager struct {
@@ -34,6 +44,14 @@ type ServerManager struct {
 	saves        *sync.Map // key: filename, value: SaveInfo，存储所有存档信息
 	loadInterval time.Duration
 	cacheTTL     time.Duration
+	stopTimeout  time.Duration
+	backend      string
+	catalog      *VersionCatalog
+	history      *PlayerHistory
+	groups       *AllowListGroups
+	groupsErr    error
+	groupsOnce   sync.Once
+	cloneJobs    sync.Map // key: job id, value: *cloneJob
 	lastLoad     time.Time
 	mu           sync.RWMutex
 }
@@ -45,6 +63,9 @@ func NewServersManager(cfg ServerManagerConfig) *ServerManager {
 	if cfg.CacheTTL == 0 {
 		cfg.CacheTTL = 5 * time.Minute
 	}
+	if cfg.Catalog == nil {
+		cfg.Catalog = NewVersionCatalog(time.Hour, NewHTTPVersionSource(DefaultVersionCatalogURL))
+	}
 
 	ssm := &ServerManager{
 		rootDir:      cfg.RootDir,
@@ -53,9 +74,19 @@ func NewServersManager(cfg ServerManagerConfig) *ServerManager {
 		saves:        &sync.Map{},
 		loadInterval: cfg.LoadInterval,
 		cacheTTL:     cfg.CacheTTL,
+		stopTimeout:  cfg.StopTimeout,
+		backend:      cfg.Backend,
+		catalog:      cfg.Catalog,
 		lastLoad:     time.Now().Add(-cfg.CacheTTL), // 设置为一个已经过期的时间，确保第一次加载会执行
 	}
 
+	history, err := NewPlayerHistory(ssm.PlayerSessionsFilePath())
+	if err != nil {
+		log.WithError(err).Error("Failed to load player history")
+		history = &PlayerHistory{file: ssm.PlayerSessionsFilePath(), open: make(map[string]PlayerEvent)}
+	}
+	ssm.history = history
+
 	// 初始化时加载一次服务器列表
 	if err := ssm.LoadServers(); err != nil {
 		log.WithError(err).Error("Failed to load servers during initialization")
@@ -72,6 +103,9 @@ func NewServersManager(cfg ServerManagerConfig) *ServerManager {
 	// 启动存档扫描
 	ssm.StartScanSaves()
 
+	// 启动定时公告
+	ssm.StartAnnouncements()
+
 	return ssm
 }
 
@@ -88,19 +122,46 @@ func (manager *ServerManager) StartLoadServers() {
 	}()
 }
 
+// StartAnnouncements 定期为运行中的服务器发送到期的公告
+func (manager *ServerManager) StartAnnouncements() {
+	go func() {
+		ticker := time.NewTicker(announceInterval)
+		defer ticker.Stop()
+
+		for now := range ticker.C {
+			for _, server := range manager.GetServers() {
+				server.runAnnouncements(now)
+			}
+		}
+	}()
+}
+
 func (manager *ServerManager) VersionsDir() string {
 	return path.Join(manager.rootDir, "versions")
 }
 
+// PlayerSessionsFilePath 所有服务器共用的玩家进出记录文件
+func (manager *ServerManager) PlayerSessionsFilePath() string {
+	return path.Join(manager.rootDir, "players", "sessions.jsonl")
+}
+
+// PlayerHistory 返回所有服务器的玩家进出记录
+func (manager *ServerManager) PlayerHistory() *PlayerHistory {
+	return manager.history
+}
+
+// VersionCatalog 返回可用版本目录
+func (manager *ServerManager) VersionCatalog() *VersionCatalog {
+	return manager.catalog
+}
+
+// LatestVersion 返回版本目录中最新的正式版
 func (manager *ServerManager) LatestVersion() (string, error) {
-	//TODO
-	/*resp, err := m.client.R().
-		Get("https://www.minecraft.net/en-us/download/server/bedrock")
+	latest, err := manager.catalog.Latest(context.Background(), ChannelStable)
 	if err != nil {
-		return "", errors.WithStack(err)
+		return "", err
 	}
-	return string(resp.Body()), nil*/
-	return "1.21.62.01", nil
+	return latest.Version, nil
 }
 
 func (manager *ServerManager) ZipFileName(version string) string {
@@ -111,9 +172,12 @@ func (manager *ServerManager) ZipFile(version string) string {
 	return path.Join(manager.VersionsDir(), manager.ZipFileName(version))
 }
 
+// ZipExist 判断版本压缩包是否已完整下载：没有正在进行的下载，且压缩包的中央目录可以读取
 func (manager *ServerManager) ZipExist(version string) bool {
-	//zip文件是否存在
-	return Exists(manager.ZipFile(version))
+	if manager.versionDownloading(version) {
+		return false
+	}
+	return validArchive(manager.ZipFile(version))
 }
 
 func (manager *ServerManager) DownloadLatestVersion() error {
@@ -130,14 +194,17 @@ func (manager *ServerManager) DownloadVersion(version string) error {
 	if existZ {
 		return nil
 	}
-	//不存在当前版本的zip文件，开始下载
-	if _, ok := manager.downloaders.Load(version); !ok {
-		manager.downloaders.Store(version, downloader.NewDownloader())
+	// 版本目录只用于查找镜像或下载地址，响应慢时不阻塞下载
+	ctx, cancel := context.WithTimeout(context.Background(), versionLookupTimeout)
+	info, found := manager.catalog.Lookup(ctx, version)
+	cancel()
+	if found && info.File != "" {
+		return manager.copyMirrorZip(info.File, version)
 	}
-	value, _ := manager.downloaders.Load(version)
+	//不存在当前版本的zip文件，开始下载
+	value, _ := manager.downloaders.LoadOrStore(version, downloader.NewDownloader())
 	d := value.(*downloader.Downloader)
-	status := d.GetCurrentStatus()
-	if status.IsDownloading {
+	if d.Running() {
 		return nil
 	} else {
 		var downloadUrl string
@@ -147,15 +214,45 @@ func (manager *ServerManager) DownloadVersion(version string) error {
 		case "windows":
 			downloadUrl = fmt.Sprintf("https://www.minecraft.net/bedrockdedicatedserver/bin-win/bedrock-server-%s.zip", version)
 		}
+		if found && info.URL != "" {
+			downloadUrl = info.URL
+		}
 		err := os.MkdirAll(manager.VersionsDir(), os.ModePerm)
 		if err != nil {
 			return err
 		}
-		d.Download(downloadUrl, manager.ZipFile(version))
+		// 重新下载时旧的校验和已失效
+		_ = os.Remove(checksumFile(manager.ZipFile(version)))
+		// 下载器先写入 .part 文件，中断后再次下载时从中断处续传。
+		// 检查后到开始下载之间可能已由其他调用开始下载，同样视为成功
+		err = d.Download(downloadUrl, manager.ZipFile(version))
+		if err != nil && !errors.Is(err, downloader.ErrDownloading) {
+			return err
+		}
 		return nil
 	}
 }
 
+// copyMirrorZip 从本地镜像复制版本压缩包，先写入临时文件，完成后再改名，避免留下不完整的压缩包
+func (manager *ServerManager) copyMirrorZip(file, version string) error {
+	err := os.MkdirAll(manager.VersionsDir(), os.ModePerm)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	_ = os.Remove(checksumFile(manager.ZipFile(version)))
+	part := manager.ZipFile(version) + ".part"
+	err = copyFile(file, part, 0666, nil)
+	if err != nil {
+		_ = os.Remove(part)
+		return err
+	}
+	err = os.Rename(part, manager.ZipFile(version))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return manager.saveChecksum(version)
+}
+
 // LoadServers 加载服务器列表
 func (manager *ServerManager) LoadServers() error {
 	manager.mu.Lock()
@@ -186,9 +283,17 @@ func (manager *ServerManager) LoadServers() error {
 		}
 
 		idStr := file.Name()[len(prefix):]
+		// 已加载的服务器直接复用，避免丢失正在运行的进程句柄
+		if server, ok := manager.servers.Load(idStr); ok {
+			newServers.Store(idStr, server)
+			continue
+		}
 		server, err := NewServer(ServerConfig{
-			ID:      idStr,
-			RootDir: path.Join(manager.rootDir, file.Name()),
+			ID:          idStr,
+			RootDir:     path.Join(manager.rootDir, file.Name()),
+			StopTimeout: manager.stopTimeout,
+			Backend:     manager.backend,
+			History:     manager.history,
 		})
 		if err != nil {
 			log.WithError(err).WithField("server_id", idStr).Error("Failed to create server")
@@ -205,6 +310,120 @@ func (manager *ServerManager) LoadServers() error {
 	return nil
 }
 
+// CreateServerConfig 新建服务器的参数
+type CreateServerConfig struct {
+	Name string
+	// Version 服务器版本，为空时使用最新版本
+	Version string
+	// Properties 下载完成后写入 server.properties 的初始配置
+	Properties map[string]string
+}
+
+// CreateServer 创建服务器目录并在后台下载服务器文件，返回新服务器
+func (manager *ServerManager) CreateServer(cfg CreateServerConfig) (*Server, error) {
+	name, err := validServerName(cfg.Name)
+	if err != nil {
+		return nil, err
+	}
+	// 初始配置在下载完成后才写入，先按示例配置校验，避免创建后才发现配置无效
+	if err = ValidateDefaultProperties(cfg.Properties); err != nil {
+		return nil, err
+	}
+	manager.mu.Lock()
+	id := manager.nextServerID()
+	rootDir := path.Join(manager.rootDir, "server-"+id)
+	err = os.MkdirAll(rootDir, os.ModePerm)
+	if err != nil {
+		manager.mu.Unlock()
+		return nil, errors.WithStack(err)
+	}
+	server, err := manager.initServer(id, rootDir, name, cfg)
+	if err != nil {
+		manager.mu.Unlock()
+		_ = os.RemoveAll(rootDir)
+		return nil, err
+	}
+	manager.servers.Store(id, server)
+	manager.mu.Unlock()
+
+	go func() {
+		err := manager.DownloadServer(id, cfg.Version)
+		if err != nil {
+			log.WithError(err).WithField("server_id", id).Error("Failed to download new server")
+		}
+		server.setCreateError(err)
+	}()
+	return server, nil
+}
+
+func (manager *ServerManager) initServer(id, rootDir, name string, cfg CreateServerConfig) (*Server, error) {
+	err := os.WriteFile(path.Join(rootDir, "servername"), []byte(name), 0666)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(cfg.Properties) > 0 {
+		w, err := dw.JsonOrDefault[map[string]string](path.Join(rootDir, initialPropertiesFile), nil)
+		if err != nil {
+			return nil, err
+		}
+		w.Data = cfg.Properties
+		if err = w.Write(); err != nil {
+			return nil, err
+		}
+	}
+	return NewServer(ServerConfig{
+		ID:          id,
+		RootDir:     rootDir,
+		StopTimeout: manager.stopTimeout,
+		Backend:     manager.backend,
+		History:     manager.history,
+	})
+}
+
+// nextServerID 返回比现有数字 id 都大的下一个 id，调用方需持有 manager.mu
+func (manager *ServerManager) nextServerID() string {
+	next := 1
+	entries, _ := os.ReadDir(manager.rootDir)
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), "server-") {
+			continue
+		}
+		if n, err := strconv.Atoi(strings.TrimPrefix(entry.Name(), "server-")); err == nil && n >= next {
+			next = n + 1
+		}
+	}
+	return strconv.Itoa(next)
+}
+
+// DeleteServer 删除服务器及其目录，并从所有白名单分组中移除
+func (manager *ServerManager) DeleteServer(id string) error {
+	server, err := manager.GetServer(id)
+	if err != nil {
+		return err
+	}
+	err = server.Delete()
+	if err != nil {
+		return err
+	}
+	manager.servers.Delete(id)
+	err = manager.editAllowListGroups(func(data map[string]*AllowListGroup) error {
+		for _, group := range data {
+			servers := make([]string, 0, len(group.Servers))
+			for _, serverID := range group.Servers {
+				if serverID != id {
+					servers = append(servers, serverID)
+				}
+			}
+			group.Servers = servers
+		}
+		return nil
+	})
+	if err != nil {
+		log.WithError(err).WithField("server_id", id).Warn("Failed to detach deleted server from allowlist groups")
+	}
+	return nil
+}
+
 func (manager *ServerManager) GetServer(id string) (*Server, error) {
 	// 检查缓存是否过期
 	if time.Since(manager.lastLoad) >= manager.cacheTTL {
@@ -250,12 +469,13 @@ func (manager *ServerManager) DownloadServer(id string, version string) error {
 			return err
 		}
 		version = latestVersion
-		s := server.(*Server)
-		s.version = latestVersion
 	}
-
+	// 已安装其他版本时走升级流程，迁移世界和配置
+	if current := server.(*Server); current.ServerExist() && current.GetVersion() != version {
+		return manager.UpgradeServer(id, version)
+	}
 	//下载版本压缩包
-	err := manager.DownloadVersion(version)
+	err := manager.fetchVersion(version)
 	if err != nil {
 		return err
 	}
@@ -274,17 +494,13 @@ func (manager *ServerManager) DownloadServer(id string, version string) error {
 		//删除lock文件
 		_ = os.Remove(s.DownloadingFilePath())
 	}()
-	//解压zip文件
-	err = os.MkdirAll(s.WorkDir(), os.ModePerm)
+	//解压zip文件，解压成功后才切换版本
+	workDir := path.Join(s.rootDir, version)
+	err = os.MkdirAll(workDir, os.ModePerm)
 	if err != nil {
 		return err
 	}
-	err = Unzip(manager.ZipFile(version), s.WorkDir())
-	if err != nil {
-		return err
-	}
-	//reload
-	err = s.Reload()
+	err = Unzip(manager.ZipFile(version), workDir)
 	if err != nil {
 		return err
 	}
@@ -292,57 +508,14 @@ func (manager *ServerManager) DownloadServer(id string, version string) error {
 	if err != nil {
 		return err
 	}
-
-	return nil
-}
-
-/*
-func (manager *ServerManager) Upgrade() error {
-	//1. 获取最新版本
-	oldServer := manager.current
-	newVersion, err := manager.LatestVersion()
-	if err != nil {
-		return err
-	}
-	//2. 若最新版本和当前版本不同，则下载最新版本
-	if newVersion == oldServer.version {
-		return nil
-	}
-	newServer, err := NewServer(ServerConfig{
-		GetVersion: newVersion,
-		RootDir: oldServer.rootDir,
-		Session: manager.session,
-	})
-	if err != nil {
-		return err
-	}
-	err = newServer.Download()
-	if err != nil {
-		return err
-	}
-	manager.current = newServer
-	//3. 复制旧版本数据文件到新版本
-	err = manager.session.Run("cp", "-r",
-		path.Join(oldServer.WorkDir(), "world"),
-		path.Join(manager.current.WorkDir()+"/"))
-	if err != nil {
-		return err
-	}
-	err = manager.session.Run("cp",
-		path.Join(oldServer.WorkDir(), oldServer.allowList.FileName()),
-		path.Join(manager.current.WorkDir(), manager.current.allowList.FileName()))
-	if err != nil {
-		return err
-	}
-	err = manager.session.Run("cp",
-		path.Join(oldServer.WorkDir(), oldServer.serverProperties.FileName()),
-		path.Join(manager.current.WorkDir(), manager.current.serverProperties.FileName()))
+	s.setVersion(version)
+	//reload
+	err = s.Reload()
 	if err != nil {
 		return err
 	}
-	return nil
+	return s.applyInitialProperties()
 }
-*/
 
 // UploadDir 返回上传目录的路径
 func (manager *ServerManager) UploadDir() string {
@@ -0,0 +1,144 @@
+package core
+
+import (
+	"os"
+	"path"
+	"testing"
+)
+
+func TestServerManager_NextServerID(t *testing.T) {
+	manager := testManager(t)
+	if id := manager.nextServerID(); id != "1" {
+		t.Fatalf("unexpected first id: %s", id)
+	}
+	for _, dir := range []string{"server-1", "server-7", "server-custom", "versions"} {
+		if err := os.MkdirAll(path.Join(manager.rootDir, dir), os.ModePerm); err != nil {
+			t.Fatalf("%+v", err)
+		}
+	}
+	if id := manager.nextServerID(); id != "8" {
+		t.Fatalf("unexpected next id: %s", id)
+	}
+}
+
+func TestServerManager_InitAndDeleteServer(t *testing.T) {
+	manager := testManager(t)
+	rootDir := path.Join(manager.rootDir, "server-1")
+	if err := os.MkdirAll(rootDir, os.ModePerm); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	server, err := manager.initServer("1", rootDir, "Survival", CreateServerConfig{
+		Properties: map[string]string{"gamemode": "creative"},
+	})
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	manager.servers.Store("1", server)
+	if name := server.GetServerName(); name != "Survival" {
+		t.Fatalf("unexpected name: %s", name)
+	}
+
+	// 模拟下载完成：写入版本文件和服务器文件后应用初始配置
+	data, err := os.ReadFile("example/server.properties")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	writeFakeServer(t, path.Join(rootDir, "fake"), fakeServerScript)
+	if err = os.WriteFile(path.Join(rootDir, "fake", "server.properties"), data, 0666); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err = os.WriteFile(server.VersionFilePath(), []byte("fake"), 0666); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err = server.Reload(); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err = server.applyInitialProperties(); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	sp, err := server.ServerProperties()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if sp.Get("gamemode") != "creative" {
+		t.Fatalf("initial properties not applied: %s", sp.Get("gamemode"))
+	}
+	if Exists(path.Join(rootDir, initialPropertiesFile)) {
+		t.Fatal("initial properties should be removed once applied")
+	}
+
+	if err = server.SetServerName("Creative"); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err = server.SetServerName(" \n"); err == nil {
+		t.Fatal("empty name should be rejected")
+	}
+	reloaded, err := NewServer(ServerConfig{ID: "1", RootDir: rootDir})
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if name := reloaded.GetServerName(); name != "Creative" {
+		t.Fatalf("unexpected persisted name: %s", name)
+	}
+
+	if err = manager.CreateAllowListGroup("friends"); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if _, err = manager.AttachAllowListGroup("friends", "1"); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err = server.Start(); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err = manager.DeleteServer("1"); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if server.process.Active() {
+		t.Fatal("server should be stopped before deletion")
+	}
+	if Exists(rootDir) {
+		t.Fatal("server directory should be removed")
+	}
+	if _, err = manager.GetServer("1"); err == nil {
+		t.Fatal("deleted server should not be found")
+	}
+	groups, _ := manager.ListAllowListGroups()
+	if len(groups) != 1 || len(groups[0].Servers) != 0 {
+		t.Fatalf("deleted server should be detached from groups: %+v", groups)
+	}
+}
+
+func TestServerManager_CreateServerInvalidProperties(t *testing.T) {
+	manager := testManager(t)
+	_, err := manager.CreateServer(CreateServerConfig{
+		Name:       "Survival",
+		Properties: map[string]string{"no-such-key": "1"},
+	})
+	if err == nil {
+		t.Fatal("unknown initial property should be rejected")
+	}
+	if Exists(path.Join(manager.rootDir, "server-1")) {
+		t.Fatal("server directory should not be created")
+	}
+}
+
+func TestServer_ApplyInitialPropertiesInvalid(t *testing.T) {
+	server := testFakeServer(t)
+	data, err := os.ReadFile("example/server.properties")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err = os.WriteFile(path.Join(server.WorkDir(), "server.properties"), data, 0666); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	file := path.Join(server.rootDir, initialPropertiesFile)
+	if err = os.WriteFile(file, []byte(`{"no-such-key":"1"}`), 0666); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err = server.applyInitialProperties(); err == nil {
+		t.Fatal("unknown initial property should be reported")
+	}
+	if Exists(file) {
+		t.Fatal("initial properties should be removed even when they fail")
+	}
+}
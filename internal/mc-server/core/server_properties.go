@@ -18,6 +18,29 @@ var (
 	}
 )
 
+// defaultServerProperties 示例配置，服务器文件下载前用于校验配置项
+//
+//go:embed example/server.properties
+var defaultServerProperties string
+
+// validateProperties 校验 data 中的每一项：键必须存在于 known 中，值需满足 serverPropertiesFilter
+func validateProperties(known map[string]string, data map[string]string) error {
+	for k, v := range data {
+		if _, ok := known[k]; !ok {
+			return errors.Errorf("unsupported key [%s]", k)
+		}
+		if filter, ok := serverPropertiesFilter[k]; ok && !utils.Contains(filter, v) {
+			return errors.Errorf("unsupported value [%s]", v)
+		}
+	}
+	return nil
+}
+
+// ValidateDefaultProperties 按示例配置校验尚未下载的服务器的配置项
+func ValidateDefaultProperties(data map[string]string) error {
+	return validateProperties(properties.MustLoadString(defaultServerProperties).Map(), data)
+}
+
 type ServerProperties struct {
 	Version string
 	rootDir string
@@ -56,7 +79,14 @@ func (sp *ServerProperties) Init() {
 				return nil, errors.WithStack(err)
 			}
 			var result bytes.Buffer
-			err = content.Execute(&result, v)
+			// 模板中的变量名为小驼峰形式，需按同样的规则转换键名
+			data := make(map[string]string)
+			if m, ok := v.(map[string]string); ok {
+				for k, val := range m {
+					data[utils.TemplateVarName(k)] = val
+				}
+			}
+			err = content.Execute(&result, data)
 			if err != nil {
 				return nil, errors.WithStack(err)
 			}
@@ -99,24 +129,22 @@ func (sp *ServerProperties) Get(key string) string {
 	return sp.Data[key]
 }
 
+// SetAll 先校验所有配置项，全部通过后才修改并写入，任一项不合法时配置保持不变
 func (sp *ServerProperties) SetAll(data map[string]string) error {
+	err := validateProperties(sp.Data, data)
+	if err != nil {
+		return err
+	}
 	for k, v := range data {
-		err := sp.Set(k, v, false)
-		if err != nil {
-			return err
-		}
+		sp.Data[k] = v
 	}
 	return sp.Write()
 }
 
 func (sp *ServerProperties) Set(k, v string, write bool) error {
-	_, kExist := sp.Data[k]
-	if !kExist {
-		return errors.Errorf("unsupported key [%s]", k)
-	}
-	filter, fExist := serverPropertiesFilter[k]
-	if fExist && !utils.Contains(filter, v) {
-		return errors.Errorf("unsupported value [%s]", v)
+	err := validateProperties(sp.Data, map[string]string{k: v})
+	if err != nil {
+		return err
 	}
 	sp.Data[k] = v
 	if write {
@@ -1,6 +1,8 @@
 package core
 
 import (
+	"os"
+	"path"
 	"testing"
 )
 
@@ -11,3 +13,54 @@ func TestNewServerProperties_GetServerName(t *testing.T) {
 	})
 	t.Log(p.GetServerName())
 }
+
+func TestServerProperties_SetAll(t *testing.T) {
+	dir := t.TempDir()
+	data, err := os.ReadFile("example/server.properties")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err = os.WriteFile(path.Join(dir, "server.properties"), data, 0666); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	sp := NewServerProperties(ServerPropertiesConfig{RootDir: dir})
+	err = sp.SetAll(map[string]string{"server-port": "19140", "gamemode": "creative"})
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	// 重新读取后修改的键生效，其他多段键名保持原值
+	reloaded := NewServerProperties(ServerPropertiesConfig{RootDir: dir})
+	if reloaded.Get("server-port") != "19140" || reloaded.Get("gamemode") != "creative" {
+		t.Fatalf("unexpected values: %s %s", reloaded.Get("server-port"), reloaded.Get("gamemode"))
+	}
+	if reloaded.Get("server-portv6") != "19133" || reloaded.Get("level-name") != sp.Get("level-name") {
+		t.Fatalf("unchanged keys should be preserved: %s %s", reloaded.Get("server-portv6"), reloaded.Get("level-name"))
+	}
+}
+
+func TestServerProperties_SetAllInvalid(t *testing.T) {
+	dir := t.TempDir()
+	data, err := os.ReadFile("example/server.properties")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err = os.WriteFile(path.Join(dir, "server.properties"), data, 0666); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	sp := NewServerProperties(ServerPropertiesConfig{RootDir: dir})
+	port := sp.Get("server-port")
+	// 任一项不合法时，合法的项也不应被修改
+	err = sp.SetAll(map[string]string{"server-port": "19140", "no-such-key": "1"})
+	if err == nil {
+		t.Fatal("unknown key should be rejected")
+	}
+	if sp.Get("server-port") != port {
+		t.Fatalf("properties changed after failed SetAll: %s", sp.Get("server-port"))
+	}
+	if err = ValidateDefaultProperties(map[string]string{"gamemode": "hardcore"}); err == nil {
+		t.Fatal("unsupported value should be rejected")
+	}
+	if err = ValidateDefaultProperties(map[string]string{"gamemode": "creative"}); err != nil {
+		t.Fatalf("%+v", err)
+	}
+}
@@ -1,6 +1,8 @@
 package core
 
 import (
+	"os"
+	"path"
 	"testing"
 )
 
@@ -15,3 +17,28 @@ func testServer(t *testing.T) *Server {
 	}
 	return server
 }
+
+// testFakeServer 创建一个以假 bedrock_server 作为当前版本的服务器
+func testFakeServer(t *testing.T) *Server {
+	rootDir := t.TempDir()
+	writeFakeServer(t, path.Join(rootDir, "fake"), fakeServerScript)
+	err := os.WriteFile(path.Join(rootDir, "version"), []byte("fake"), 0666)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	server, err := NewServer(ServerConfig{
+		ID:      "test",
+		RootDir: rootDir,
+	})
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	t.Cleanup(func() {
+		// 以请求的方式关闭，避免退出被当作崩溃记录并在临时目录中写入文件
+		if server.process.Active() {
+			_, _ = server.Shutdown()
+		}
+		server.cancelRestart()
+	})
+	return server
+}
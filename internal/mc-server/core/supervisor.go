@@ -0,0 +1,394 @@
+package core
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/candbright/go-log/log"
+	"github.com/pkg/errors"
+)
+
+// maxConsoleLogSize 会话日志（console.log 或 tmux、screen 的日志文件）超过该大小时轮转到 <文件名>.1，
+// 只保留一份旧日志
+var maxConsoleLogSize int64 = 16 * 1024 * 1024
+
+const (
+	consoleInFile  = "console.in"
+	consoleLogFile = "console.log"
+	// drainTimeout 进程退出后等待剩余输出读取完毕的最长时间
+	drainTimeout = 2 * time.Second
+	// attachPollInterval 接管的进程不是面板的子进程，只能轮询其是否存活
+	attachPollInterval = time.Second
+)
+
+type SupervisorConfig struct {
+	Dir      string
+	ExecFile string
+	Env      []string
+	// IODir 非空且系统支持命名管道时，子进程的标准输入使用该目录下的命名管道、输出写入日志文件，
+	// 面板退出不会影响子进程，重启后可以通过 Attach 重新接管；否则使用匿名管道
+	IODir string
+}
+
+// Supervisor 通过 os/exec 直接托管 bedrock_server 子进程，持有其标准输入、输出和进程句柄
+type Supervisor struct {
+	dir      string
+	execFile string
+	env      []string
+	ioDir    string
+
+	mu        sync.Mutex
+	process   *os.Process
+	stdin     io.WriteCloser
+	done      chan struct{}
+	startedAt time.Time
+	procStart uint64
+	exitCode  int
+	exitErr   error
+	handlers  []func(line string)
+}
+
+func NewSupervisor(cfg SupervisorConfig) *Supervisor {
+	return &Supervisor{
+		dir:      cfg.Dir,
+		execFile: cfg.ExecFile,
+		env:      cfg.Env,
+		ioDir:    cfg.IODir,
+		exitCode: -1,
+	}
+}
+
+// OnOutput 注册输出处理函数，子进程的 stdout/stderr 每输出一行都会回调一次
+func (s *Supervisor) OnOutput(handler func(line string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers = append(s.handlers, handler)
+}
+
+func (s *Supervisor) emit(line string) {
+	line = strings.TrimRight(line, "\r\n")
+	s.mu.Lock()
+	handlers := s.handlers
+	s.mu.Unlock()
+	for _, handler := range handlers {
+		handler(line)
+	}
+}
+
+func (s *Supervisor) consoleIn() string {
+	return path.Join(s.ioDir, consoleInFile)
+}
+
+func (s *Supervisor) consoleLog() string {
+	return path.Join(s.ioDir, consoleLogFile)
+}
+
+// detachable 是否使用命名管道和日志文件与子进程通信
+func (s *Supervisor) detachable() bool {
+	if s.ioDir == "" {
+		return false
+	}
+	if err := makeFifo(s.consoleIn()); err != nil {
+		log.WithError(err).Debug("named pipe unavailable, falling back to anonymous pipes")
+		return false
+	}
+	return true
+}
+
+func (s *Supervisor) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running() {
+		return errors.New("server process is already running")
+	}
+
+	cmd := exec.Command(s.execFile)
+	cmd.Dir = s.dir
+	cmd.Env = append(os.Environ(), s.env...)
+	setProcAttr(cmd)
+
+	var stdin io.WriteCloser
+	var output func(exited chan struct{})
+	var closeAfterStart []io.Closer
+	if s.detachable() {
+		// 以读写方式打开命名管道：子进程继承后永远不会读到 EOF，面板也无需等待读端即可打开
+		fifo, err := os.OpenFile(s.consoleIn(), os.O_RDWR, 0)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		logFile, err := os.OpenFile(s.consoleLog(), os.O_CREATE|os.O_TRUNC|os.O_WRONLY|os.O_APPEND, 0666)
+		if err != nil {
+			_ = fifo.Close()
+			return errors.WithStack(err)
+		}
+		cmd.Stdin = fifo
+		cmd.Stdout = logFile
+		cmd.Stderr = logFile
+		stdin = fifo
+		closeAfterStart = append(closeAfterStart, logFile)
+		output = func(exited chan struct{}) {
+			s.followLog(0, exited)
+		}
+	} else {
+		pipe, err := cmd.StdinPipe()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		// stdout 与 stderr 合并到同一个管道，保证输出顺序与控制台一致
+		reader, writer, err := os.Pipe()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		cmd.Stdout = writer
+		cmd.Stderr = writer
+		stdin = pipe
+		closeAfterStart = append(closeAfterStart, writer)
+		output = func(chan struct{}) {
+			s.readOutput(reader)
+		}
+	}
+
+	log.Infof("Running cmd %v", cmd.Args)
+	err := cmd.Start()
+	for _, closer := range closeAfterStart {
+		_ = closer.Close()
+	}
+	if err != nil {
+		_ = stdin.Close()
+		return errors.WithStack(err)
+	}
+
+	done := make(chan struct{})
+	s.process = cmd.Process
+	s.stdin = stdin
+	s.done = done
+	s.startedAt = time.Now()
+	s.procStart, _ = procStartTime(cmd.Process.Pid)
+	s.exitCode = -1
+	s.exitErr = nil
+
+	exited, drained := make(chan struct{}), make(chan struct{})
+	go func() {
+		output(exited)
+		close(drained)
+	}()
+	go s.wait(cmd, exited, drained, done)
+	return nil
+}
+
+// Attach 接管上次由面板启动、仍在运行的子进程，通过 pid 与进程启动时间识别，避免误认被复用的 pid
+func (s *Supervisor) Attach(state SessionState) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running() || state.Pid <= 0 || s.ioDir == "" {
+		return false
+	}
+	procStart, ok := procStartTime(state.Pid)
+	if !ok || procStart != state.ProcStart {
+		return false
+	}
+	process, err := os.FindProcess(state.Pid)
+	if err != nil {
+		return false
+	}
+	fifo, err := os.OpenFile(s.consoleIn(), os.O_RDWR, 0)
+	if err != nil {
+		log.WithError(err).Warn("open console pipe of attached process failed")
+		return false
+	}
+
+	done := make(chan struct{})
+	s.process = process
+	s.stdin = fifo
+	s.done = done
+	s.startedAt = state.StartedAt
+	s.procStart = procStart
+	s.exitCode = -1
+	s.exitErr = nil
+
+	// 在返回前确定读取位置，之后的输出都不会遗漏
+	offset := fileSize(s.consoleLog())
+	exited, drained := make(chan struct{}), make(chan struct{})
+	go func() {
+		s.followLog(offset, exited)
+		close(drained)
+	}()
+	go s.poll(state.Pid, procStart, fifo, exited, drained, done)
+	log.WithField("pid", state.Pid).Info("attached to running server process")
+	return true
+}
+
+func (s *Supervisor) State() SessionState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := SessionState{
+		Backend:   BackendNative,
+		ProcStart: s.procStart,
+		StartedAt: s.startedAt,
+	}
+	if s.process != nil {
+		state.Pid = s.process.Pid
+	}
+	return state
+}
+
+// followLog 读取日志文件直到 exited 关闭且已读到文件末尾
+func (s *Supervisor) followLog(offset int64, exited chan struct{}) {
+	followFile(s.consoleLog(), offset, maxConsoleLogSize, func() bool {
+		select {
+		case <-exited:
+			return true
+		default:
+			return false
+		}
+	}, s.emit)
+}
+
+func (s *Supervisor) readOutput(reader io.ReadCloser) {
+	defer reader.Close()
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		s.emit(scanner.Text())
+	}
+}
+
+// wait 等待子进程退出，剩余输出读取完毕后再关闭 done，保证退出通知晚于最后的输出
+func (s *Supervisor) wait(cmd *exec.Cmd, exited, drained, done chan struct{}) {
+	err := cmd.Wait()
+	close(exited)
+	waitDrained(drained)
+	s.mu.Lock()
+	s.exitErr = err
+	if cmd.ProcessState != nil {
+		s.exitCode = cmd.ProcessState.ExitCode()
+	}
+	_ = s.stdin.Close()
+	s.mu.Unlock()
+	close(done)
+	log.WithField("pid", cmd.Process.Pid).WithField("exit_code", s.ExitCode()).Info("server process exited")
+}
+
+// poll 轮询接管的进程直到其退出，退出码无法获取
+func (s *Supervisor) poll(pid int, procStart uint64, stdin io.Closer, exited, drained, done chan struct{}) {
+	for {
+		if current, ok := procStartTime(pid); !ok || current != procStart {
+			break
+		}
+		time.Sleep(attachPollInterval)
+	}
+	close(exited)
+	waitDrained(drained)
+	s.mu.Lock()
+	_ = stdin.Close()
+	s.mu.Unlock()
+	close(done)
+	log.WithField("pid", pid).Info("attached server process exited")
+}
+
+func waitDrained(drained chan struct{}) {
+	timer := time.NewTimer(drainTimeout)
+	defer timer.Stop()
+	select {
+	case <-drained:
+	case <-timer.C:
+	}
+}
+
+func (s *Supervisor) running() bool {
+	if s.done == nil {
+		return false
+	}
+	select {
+	case <-s.done:
+		return false
+	default:
+		return true
+	}
+}
+
+func (s *Supervisor) Active() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running()
+}
+
+// Done 返回当前子进程的退出通知，进程从未启动时返回 nil
+func (s *Supervisor) Done() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.done
+}
+
+func (s *Supervisor) Pid() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.process == nil {
+		return 0
+	}
+	return s.process.Pid
+}
+
+func (s *Supervisor) StartedAt() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.startedAt
+}
+
+// ExitCode 返回最近一次退出的退出码，进程仍在运行、从未启动或为接管的进程时返回 -1
+func (s *Supervisor) ExitCode() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.exitCode
+}
+
+func (s *Supervisor) ExitErr() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.exitErr
+}
+
+// Send 向子进程标准输入写入一行命令
+func (s *Supervisor) Send(line string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.running() {
+		return errors.New("server process is not running")
+	}
+	_, err := io.WriteString(s.stdin, line+"\n")
+	return errors.WithStack(err)
+}
+
+// Terminate 请求子进程退出，不等待其结束
+func (s *Supervisor) Terminate() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.running() {
+		return errors.New("server process is not running")
+	}
+	return errors.WithStack(terminateProcess(s.process))
+}
+
+// Kill 强制结束子进程并等待其退出
+func (s *Supervisor) Kill() error {
+	s.mu.Lock()
+	if !s.running() {
+		s.mu.Unlock()
+		return errors.New("server process is not running")
+	}
+	process := s.process
+	done := s.done
+	s.mu.Unlock()
+
+	if err := process.Kill(); err != nil {
+		return errors.WithStack(err)
+	}
+	<-done
+	return nil
+}
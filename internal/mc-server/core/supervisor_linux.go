@@ -0,0 +1,57 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// setProcAttr 让子进程使用独立的进程组，避免面板收到的 Ctrl-C 等信号直接传递给服务器
+func setProcAttr(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+func terminateProcess(process *os.Process) error {
+	return process.Signal(syscall.SIGTERM)
+}
+
+// makeFifo 创建命名管道，已存在时直接复用
+func makeFifo(file string) error {
+	info, err := os.Stat(file)
+	if err == nil {
+		if info.Mode()&os.ModeNamedPipe != 0 {
+			return nil
+		}
+		return errors.Errorf("%s exists and is not a named pipe", file)
+	}
+	return errors.WithStack(syscall.Mkfifo(file, 0600))
+}
+
+// procStartTime 读取 /proc/<pid>/stat 中的进程启动时间（系统启动以来的时钟数），进程不存在或已成为僵尸进程时返回 false
+func procStartTime(pid int) (uint64, bool) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, false
+	}
+	// 第 2 个字段是括号包裹的进程名，可能包含空格，从最后一个右括号之后开始解析
+	stat := string(data)
+	i := strings.LastIndex(stat, ")")
+	if i < 0 {
+		return 0, false
+	}
+	fields := strings.Fields(stat[i+1:])
+	// fields[0] 为第 3 个字段（进程状态），启动时间是第 22 个字段
+	if len(fields) < 20 || fields[0] == "Z" {
+		return 0, false
+	}
+	start, err := strconv.ParseUint(fields[19], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return start, true
+}
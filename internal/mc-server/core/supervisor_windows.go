@@ -0,0 +1,30 @@
+package core
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+const createNewProcessGroup = 0x00000200
+
+// setProcAttr 让子进程使用独立的进程组，避免面板收到的 Ctrl-C 等信号直接传递给服务器
+func setProcAttr(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: createNewProcessGroup}
+}
+
+// terminateProcess windows 不支持 SIGTERM，直接结束进程
+func terminateProcess(process *os.Process) error {
+	return process.Kill()
+}
+
+// makeFifo windows 不支持命名管道文件，子进程只能使用匿名管道，面板重启后无法重新接管
+func makeFifo(string) error {
+	return errors.New("named pipe is not supported on windows")
+}
+
+func procStartTime(int) (uint64, bool) {
+	return 0, false
+}
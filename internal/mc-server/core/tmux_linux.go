@@ -2,16 +2,38 @@ package core
 
 import (
 	"fmt"
-	"github.com/pkg/errors"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
 )
 
+// ansiRegex 匹配终端控制序列，tmux 捕获的输出中需要去除
+var ansiRegex = regexp.MustCompile(`\x1b\[[0-9;?]*[a-zA-Z]`)
+
 type Tmux struct {
 	Name string
 }
 
 func (s Tmux) Create() error {
-	return errors.WithStack(Command("tmux", "new", "-s", s.Name).Run())
+	return errors.WithStack(Command("tmux", "new", "-d", "-s", s.Name).Run())
+}
+
+// CreateIn 在 dir 目录下创建会话，窗格足够宽以避免长日志行被终端折行
+func (s Tmux) CreateIn(dir string) error {
+	return errors.WithStack(Command("tmux", "new", "-d", "-s", s.Name, "-c", dir, "-x", "1000", "-y", "50").Run())
+}
+
+// PipeTo 将会话窗格的输出追加到 file，以追加方式写入才能在轮转截断后从头继续写
+func (s Tmux) PipeTo(file string) error {
+	return errors.WithStack(Command("tmux", "pipe-pane", "-t", s.Name, "-o",
+		fmt.Sprintf("cat >> '%s'", file)).Run())
 }
 
 func (s Tmux) Exists() bool {
@@ -19,11 +41,131 @@ func (s Tmux) Exists() bool {
 	return err == nil
 }
 
+// PanePid 返回会话窗格中运行的进程号
+func (s Tmux) PanePid() (int, error) {
+	output, err := Command("tmux", "display-message", "-p", "-t", s.Name, "#{pane_pid}").Output()
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(output)))
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return pid, nil
+}
+
 func (s Tmux) Exit() error {
 	return errors.WithStack(Command("tmux", "kill-session", "-t", s.Name).Run())
 }
 
+// ExecCmd 发送一行命令。文本以 -l 按字面发送，避免 "Enter"、"C-c" 等被当作按键名，再单独发送回车
 func (s Tmux) ExecCmd(arg ...string) error {
-	return errors.WithStack(Command("tmux", "send-keys", "-t", s.Name,
-		fmt.Sprintf("\"%s\"", strings.Join(arg, " ")), "Enter").Run())
+	err := Command("tmux", "send-keys", "-l", "-t", s.Name, "--", strings.Join(arg, " ")).Run()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(Command("tmux", "send-keys", "-t", s.Name, "Enter").Run())
+}
+
+// tmuxSession 将 Tmux 适配为进程会话后端，输出通过 pipe-pane 写入日志文件后再逐行读取
+type tmuxSession struct {
+	Tmux
+	dir      string
+	execFile string
+
+	mu       sync.Mutex
+	handlers []func(line string)
+}
+
+func (s *tmuxSession) LogFile() string {
+	return path.Join(s.dir, s.Name+".log")
+}
+
+func (s *tmuxSession) Start() error {
+	// 日志以追加方式写入，先删除上次运行的日志
+	_ = os.Remove(s.LogFile())
+	err := s.CreateIn(s.dir)
+	if err != nil {
+		return err
+	}
+	// 先接管窗格输出再启动服务器，避免丢失启动阶段的日志
+	err = s.PipeTo(s.LogFile())
+	if err != nil {
+		return err
+	}
+	go s.follow(0)
+	// exec 替换掉 shell，服务器退出后会话随之结束
+	return s.ExecCmd(fmt.Sprintf("exec env LD_LIBRARY_PATH=. '%s'", s.execFile))
+}
+
+func (s *tmuxSession) State() SessionState {
+	state := SessionState{
+		Backend:   BackendTmux,
+		Session:   s.Name,
+		StartedAt: time.Now(),
+	}
+	if pid, err := s.PanePid(); err == nil {
+		state.Pid = pid
+	}
+	return state
+}
+
+// Attach tmux 会话在面板退出后依然存在，会话名一致且仍存在即可接管，从日志文件末尾继续读取输出
+func (s *tmuxSession) Attach(state SessionState) bool {
+	if state.Session != s.Name || !s.Exists() {
+		return false
+	}
+	go s.follow(fileSize(s.LogFile()))
+	return true
+}
+
+func (s *tmuxSession) Active() bool {
+	return s.Exists()
+}
+
+func (s *tmuxSession) Send(line string) error {
+	return s.ExecCmd(line)
+}
+
+// Terminate 服务器通过 exec 替换了窗格中的 shell，窗格进程即服务器进程
+func (s *tmuxSession) Terminate() error {
+	pid, err := s.PanePid()
+	if err != nil {
+		return err
+	}
+	return errors.WithStack(syscall.Kill(pid, syscall.SIGTERM))
+}
+
+func (s *tmuxSession) Kill() error {
+	return s.Exit()
+}
+
+func (s *tmuxSession) OnOutput(handler func(line string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers = append(s.handlers, handler)
+}
+
+func (s *tmuxSession) emit(line string) {
+	line = strings.ReplaceAll(ansiRegex.ReplaceAllString(line, ""), "\r", "")
+	line = strings.TrimRight(line, "\n")
+	s.mu.Lock()
+	handlers := s.handlers
+	s.mu.Unlock()
+	for _, handler := range handlers {
+		handler(line)
+	}
+}
+
+// follow 从 offset 处持续读取日志文件直到会话结束
+func (s *tmuxSession) follow(offset int64) {
+	var lastCheck time.Time
+	followFile(s.LogFile(), offset, maxConsoleLogSize, func() bool {
+		// 空闲时约 2 秒检查一次会话是否仍然存在
+		if time.Since(lastCheck) < 2*time.Second {
+			return false
+		}
+		lastCheck = time.Now()
+		return !s.Exists()
+	}, s.emit)
 }
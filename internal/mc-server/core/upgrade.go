@@ -0,0 +1,336 @@
+package core
+
+import (
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/candbright/go-log/log"
+	"github.com/candbright/go-server/pkg/downloader"
+	"github.com/pkg/errors"
+)
+
+// carriedFiles 升级时从旧版本目录复制到新版本目录的文件和目录
+var carriedFiles = []string{"worlds", "allowlist.json", "permissions.json"}
+
+// packDirs 存放行为包和资源包的目录，升级时只复制新版本中不存在的包，避免覆盖新版本自带的原版包
+var packDirs = []string{"behavior_packs", "resource_packs", "development_behavior_packs", "development_resource_packs"}
+
+// UpgradeStatus 服务器最近一次升级的状态
+type UpgradeStatus struct {
+	State       string `json:"state"`
+	FromVersion string `json:"from_version"`
+	ToVersion   string `json:"to_version"`
+	// AddedProperties 新版本 server.properties 中新增、使用默认值的配置项
+	AddedProperties []string   `json:"added_properties,omitempty"`
+	Error           string     `json:"error,omitempty"`
+	StartedAt       time.Time  `json:"started_at"`
+	FinishedAt      *time.Time `json:"finished_at,omitempty"`
+}
+
+// upgradeTracker 升级和回滚共用的状态，两者都会向版本目录复制文件并改写 version 文件，
+// 同一服务器同一时间只允许进行其中一个
+type upgradeTracker struct {
+	mu     sync.Mutex
+	status *UpgradeStatus
+	// rollbackTo 正在回滚到的版本，没有回滚在进行时为空
+	rollbackTo string
+}
+
+// busy 返回正在进行的升级或回滚，调用方需持有 mu
+func (tracker *upgradeTracker) busy() error {
+	if tracker.status != nil && tracker.status.State == JobRunning {
+		return errors.Errorf("server is already upgrading to [%s]", tracker.status.ToVersion)
+	}
+	if tracker.rollbackTo != "" {
+		return errors.Errorf("server is rolling back to [%s]", tracker.rollbackTo)
+	}
+	return nil
+}
+
+// UpgradeStatus 返回最近一次升级的状态，从未升级过时返回 false
+func (server *Server) UpgradeStatus() (UpgradeStatus, bool) {
+	server.upgrade.mu.Lock()
+	defer server.upgrade.mu.Unlock()
+	if server.upgrade.status == nil {
+		return UpgradeStatus{}, false
+	}
+	return *server.upgrade.status, true
+}
+
+// beginUpgrade 记录升级开始，已有升级或回滚在进行时返回错误
+func (server *Server) beginUpgrade(version string) error {
+	server.upgrade.mu.Lock()
+	defer server.upgrade.mu.Unlock()
+	if err := server.upgrade.busy(); err != nil {
+		return err
+	}
+	server.upgrade.status = &UpgradeStatus{
+		State:       JobRunning,
+		FromVersion: server.GetVersion(),
+		ToVersion:   version,
+		StartedAt:   time.Now(),
+	}
+	return nil
+}
+
+func (server *Server) finishUpgrade(added []string, err error) {
+	server.upgrade.mu.Lock()
+	defer server.upgrade.mu.Unlock()
+	now := time.Now()
+	status := server.upgrade.status
+	status.FinishedAt = &now
+	status.AddedProperties = added
+	status.State = JobDone
+	if err != nil {
+		status.State = JobFailed
+		status.Error = err.Error()
+	}
+}
+
+// beginRollback 记录回滚开始，已有升级或回滚在进行时返回错误
+func (server *Server) beginRollback(version string) error {
+	server.upgrade.mu.Lock()
+	defer server.upgrade.mu.Unlock()
+	if err := server.upgrade.busy(); err != nil {
+		return err
+	}
+	server.upgrade.rollbackTo = version
+	return nil
+}
+
+func (server *Server) finishRollback() {
+	server.upgrade.mu.Lock()
+	defer server.upgrade.mu.Unlock()
+	server.upgrade.rollbackTo = ""
+}
+
+// RollingBack 返回正在回滚到的版本，没有回滚在进行时返回 false
+func (server *Server) RollingBack() (string, bool) {
+	server.upgrade.mu.Lock()
+	defer server.upgrade.mu.Unlock()
+	return server.upgrade.rollbackTo, server.upgrade.rollbackTo != ""
+}
+
+// UpgradeServer 将服务器升级到指定版本，version 为空时使用最新版本。
+// 下载新版本后关闭服务器，把世界、白名单、权限、自定义资源包和 server.properties 迁移到新版本目录，
+// 切换 version 文件，原本运行中的服务器会重新启动。旧版本目录保留，用于回滚。
+func (manager *ServerManager) UpgradeServer(id, version string) error {
+	server, version, err := manager.prepareUpgrade(id, version)
+	if err != nil {
+		return err
+	}
+	return manager.runUpgrade(server, version)
+}
+
+// StartUpgrade 校验后在后台升级服务器，返回升级开始时的状态，进度可通过 Server.UpgradeStatus 查询
+func (manager *ServerManager) StartUpgrade(id, version string) (UpgradeStatus, error) {
+	server, version, err := manager.prepareUpgrade(id, version)
+	if err != nil {
+		return UpgradeStatus{}, err
+	}
+	status, _ := server.UpgradeStatus()
+	go func() {
+		_ = manager.runUpgrade(server, version)
+	}()
+	return status, nil
+}
+
+// prepareUpgrade 校验目标版本并记录升级开始，返回实际升级到的版本
+func (manager *ServerManager) prepareUpgrade(id, version string) (*Server, string, error) {
+	server, err := manager.GetServer(id)
+	if err != nil {
+		return nil, "", err
+	}
+	if version == "" {
+		version, err = manager.LatestVersion()
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	if !server.ServerExist() {
+		return nil, "", errors.Errorf("server [%s] has no installed version", id)
+	}
+	if version == server.GetVersion() {
+		return nil, "", errors.Errorf("server [%s] is already on version [%s]", id, version)
+	}
+	if err = server.beginUpgrade(version); err != nil {
+		return nil, "", err
+	}
+	return server, version, nil
+}
+
+func (manager *ServerManager) runUpgrade(server *Server, version string) error {
+	added, err := manager.upgradeServer(server, version)
+	server.finishUpgrade(added, err)
+	logger := log.WithField("server_id", server.id).WithField("version", version)
+	if err != nil {
+		logger.WithError(err).Error("upgrade server failed")
+		return err
+	}
+	logger.Info("server upgraded")
+	return nil
+}
+
+func (manager *ServerManager) upgradeServer(server *Server, version string) ([]string, error) {
+	err := manager.fetchVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	oldDir := server.WorkDir()
+	newDir := path.Join(server.rootDir, version)
+
+	wasRunning := server.Active()
+	if wasRunning {
+		if _, err = server.Shutdown(); err != nil {
+			return nil, err
+		}
+	}
+	// 迁移期间加锁，防止服务器被启动
+	err = os.WriteFile(server.DownloadingFilePath(), nil, 0666)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	added, err := migrateVersion(manager.ZipFile(version), oldDir, newDir)
+	_ = os.Remove(server.DownloadingFilePath())
+	if err != nil {
+		_ = os.RemoveAll(newDir)
+		if wasRunning {
+			if startErr := server.Start(); startErr != nil {
+				log.WithError(startErr).WithField("server_id", server.id).Error("restart server after failed upgrade failed")
+			}
+		}
+		return nil, err
+	}
+	if err = server.switchVersion(version); err != nil {
+		return added, err
+	}
+	if wasRunning {
+		return added, server.Start()
+	}
+	return added, nil
+}
+
+// switchVersion 写入 version 文件并基于新版本目录重建进程
+func (server *Server) switchVersion(version string) error {
+	err := os.WriteFile(server.VersionFilePath(), []byte(version), 0666)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	server.setVersion(version)
+	return server.Reload()
+}
+
+// migrateVersion 将 zip 解压到 newDir，并从 oldDir 迁移数据和配置，返回新版本新增的配置项
+func migrateVersion(zip, oldDir, newDir string) ([]string, error) {
+	// 目录可能残留自旧的安装，重新解压保证文件与新版本一致
+	err := os.RemoveAll(newDir)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	err = os.MkdirAll(newDir, os.ModePerm)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	err = Unzip(zip, newDir)
+	if err != nil {
+		return nil, err
+	}
+	return carryOver(oldDir, newDir)
+}
+
+// carryOver 将 oldDir 的世界、白名单、权限和 newDir 中缺少的资源包复制到 newDir，
+// 并合并 server.properties，返回 newDir 中新增的配置项
+func carryOver(oldDir, newDir string) ([]string, error) {
+	for _, name := range carriedFiles {
+		if err := copyIfExists(path.Join(oldDir, name), path.Join(newDir, name)); err != nil {
+			return nil, err
+		}
+	}
+	for _, dir := range packDirs {
+		entries, err := os.ReadDir(path.Join(oldDir, dir))
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			target := path.Join(newDir, dir, entry.Name())
+			if Exists(target) {
+				continue
+			}
+			if err := copyIfExists(path.Join(oldDir, dir, entry.Name()), target); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return mergeServerProperties(oldDir, newDir)
+}
+
+// mergeServerProperties 以新版本的 server.properties 为模板，沿用旧版本中仍然存在的配置项，
+// 返回旧版本中没有、使用新版本默认值的配置项
+func mergeServerProperties(oldDir, newDir string) ([]string, error) {
+	if !Exists(path.Join(oldDir, "server.properties")) || !Exists(path.Join(newDir, "server.properties")) {
+		return nil, nil
+	}
+	oldSP := NewServerProperties(ServerPropertiesConfig{RootDir: oldDir})
+	newSP := NewServerProperties(ServerPropertiesConfig{RootDir: newDir})
+	added := make([]string, 0)
+	values := make(map[string]string)
+	for key := range newSP.GetAll() {
+		if value, ok := oldSP.GetAll()[key]; ok {
+			values[key] = value
+		} else {
+			added = append(added, key)
+		}
+	}
+	sort.Strings(added)
+	return added, newSP.SetAll(values)
+}
+
+func copyIfExists(src, dst string) error {
+	info, err := os.Stat(src)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if info.IsDir() {
+		_ = os.RemoveAll(dst)
+		return copyDir(src, dst, nil, nil)
+	}
+	return copyFile(src, dst, info.Mode().Perm(), nil)
+}
+
+// fetchVersion 确保版本压缩包已完整下载，下载中时等待下载结束
+func (manager *ServerManager) fetchVersion(version string) error {
+	err := manager.DownloadVersion(version)
+	if err != nil {
+		return err
+	}
+	value, ok := manager.downloaders.Load(version)
+	if !ok {
+		return nil
+	}
+	d := value.(*downloader.Downloader)
+	for d.Running() {
+		time.Sleep(time.Second)
+	}
+	// 压缩包可能已由之前的下载或本地镜像提供，此时忽略下载器中残留的错误
+	if !manager.ZipExist(version) {
+		err = d.GetCurrentStatus().Err
+		if err == nil {
+			err = errors.New("archive is incomplete")
+		}
+		// 已下载的部分保留在 .part 文件中，下次下载时续传
+		return errors.Wrapf(err, "download version [%s]", version)
+	}
+	// 本次下载成功时记录 SHA-256，之后用于校验压缩包；开始下载时已删除旧的记录
+	if d.GetCurrentStatus().Err == nil && !Exists(checksumFile(manager.ZipFile(version))) {
+		if err = manager.saveChecksum(version); err != nil {
+			log.WithError(err).WithField("version", version).Warn("save archive checksum failed")
+		}
+	}
+	return nil
+}
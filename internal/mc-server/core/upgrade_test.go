@@ -0,0 +1,151 @@
+package core
+
+import (
+	"archive/zip"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTestZip 按 files 生成版本压缩包，以 / 结尾的名称为目录
+func writeTestZip(t *testing.T, file string, files map[string]string) {
+	if err := os.MkdirAll(path.Dir(file), os.ModePerm); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	for name, content := range files {
+		header := &zip.FileHeader{Name: name, Method: zip.Deflate}
+		header.SetMode(0755)
+		fw, err := w.CreateHeader(header)
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		if _, err = fw.Write([]byte(content)); err != nil {
+			t.Fatalf("%+v", err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatalf("%+v", err)
+	}
+}
+
+func TestMergeServerProperties(t *testing.T) {
+	oldDir, newDir := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(path.Join(oldDir, "server.properties"), []byte("server-name=Mine\nremoved-key=1\n"), 0666); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err := os.WriteFile(path.Join(newDir, "server.properties"), []byte("server-name=Dedicated Server\nnew-key=abc\n"), 0666); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	added, err := mergeServerProperties(oldDir, newDir)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if len(added) != 1 || added[0] != "new-key" {
+		t.Fatalf("unexpected added keys: %v", added)
+	}
+	sp := NewServerProperties(ServerPropertiesConfig{RootDir: newDir})
+	if sp.Get("server-name") != "Mine" || sp.Get("new-key") != "abc" {
+		t.Fatalf("unexpected properties: %v", sp.GetAll())
+	}
+	if _, ok := sp.GetAll()["removed-key"]; ok {
+		t.Fatal("keys removed in the new version should not be carried over")
+	}
+}
+
+func TestServerManager_UpgradeServer(t *testing.T) {
+	server := testFakeServer(t)
+	manager := testManager(t, server)
+	example, err := os.ReadFile("example/server.properties")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	properties := strings.Replace(string(example), "server-port=19132", "server-port=19200", 1)
+	oldDir := server.WorkDir()
+	for name, content := range map[string]string{
+		"server.properties":                        properties,
+		"allowlist.json":                           `[{"name":"Steve"}]`,
+		"permissions.json":                         `[]`,
+		"worlds/Bedrock level/level.dat":           "level",
+		"behavior_packs/custom/manifest.json":      "custom",
+		"behavior_packs/vanilla/manifest.json":     "old vanilla",
+		"development_resource_packs/dev/pack.json": "dev",
+	} {
+		file := path.Join(oldDir, name)
+		if err = os.MkdirAll(path.Dir(file), os.ModePerm); err != nil {
+			t.Fatalf("%+v", err)
+		}
+		if err = os.WriteFile(file, []byte(content), 0666); err != nil {
+			t.Fatalf("%+v", err)
+		}
+	}
+	writeTestZip(t, manager.ZipFile("fake2"), map[string]string{
+		"bedrock_server":                       fakeServerScript,
+		"server.properties":                    string(example) + "new-key=abc\n",
+		"behavior_packs/vanilla/manifest.json": "new vanilla",
+	})
+	if err = server.Reload(); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err = server.Start(); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if _, err = server.process.WaitReady(5 * time.Second); err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if err = manager.UpgradeServer(server.id, "fake"); err == nil {
+		t.Fatal("upgrading to the current version should be rejected")
+	}
+	if err = manager.UpgradeServer(server.id, "fake2"); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	status, ok := server.UpgradeStatus()
+	if !ok || status.State != JobDone || status.FromVersion != "fake" || status.ToVersion != "fake2" {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+	if len(status.AddedProperties) != 1 || status.AddedProperties[0] != "new-key" {
+		t.Fatalf("unexpected added properties: %v", status.AddedProperties)
+	}
+	if server.GetVersion() != "fake2" || server.WorkDir() != path.Join(server.rootDir, "fake2") {
+		t.Fatalf("version not switched: %s", server.GetVersion())
+	}
+	if version, _ := os.ReadFile(server.VersionFilePath()); string(version) != "fake2" {
+		t.Fatalf("unexpected version file: %s", version)
+	}
+	if !Exists(path.Join(oldDir, "worlds")) {
+		t.Fatal("old version directory should be kept")
+	}
+	for name, want := range map[string]string{
+		"allowlist.json":                           `[{"name":"Steve"}]`,
+		"worlds/Bedrock level/level.dat":           "level",
+		"behavior_packs/custom/manifest.json":      "custom",
+		"behavior_packs/vanilla/manifest.json":     "new vanilla",
+		"development_resource_packs/dev/pack.json": "dev",
+	} {
+		data, err := os.ReadFile(path.Join(server.WorkDir(), name))
+		if err != nil || string(data) != want {
+			t.Errorf("%s: got %q, %v", name, data, err)
+		}
+	}
+	sp, err := server.ServerProperties()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if sp.Get("server-port") != "19200" || sp.Get("new-key") != "abc" {
+		t.Fatalf("unexpected properties: %s %s", sp.Get("server-port"), sp.Get("new-key"))
+	}
+	if !server.Active() {
+		t.Fatal("server should be restarted after upgrade")
+	}
+	if _, err = server.process.WaitReady(5 * time.Second); err != nil {
+		t.Fatalf("%+v", err)
+	}
+}
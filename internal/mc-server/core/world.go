@@ -0,0 +1,154 @@
+package core
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/candbright/go-server/internal/mc-server/utils"
+	"github.com/pkg/errors"
+)
+
+const (
+	GameruleBool = "bool"
+	GameruleInt  = "int"
+)
+
+// Gamerules bedrock 支持的游戏规则及其取值类型，key 为小写的规则名
+var Gamerules = map[string]string{
+	"commandblockoutput":        GameruleBool,
+	"commandblocksenabled":      GameruleBool,
+	"dodaylightcycle":           GameruleBool,
+	"doentitydrops":             GameruleBool,
+	"dofiretick":                GameruleBool,
+	"doimmediaterespawn":        GameruleBool,
+	"doinsomnia":                GameruleBool,
+	"dolimitedcrafting":         GameruleBool,
+	"domobloot":                 GameruleBool,
+	"domobspawning":             GameruleBool,
+	"dotiledrops":               GameruleBool,
+	"doweathercycle":            GameruleBool,
+	"drowningdamage":            GameruleBool,
+	"falldamage":                GameruleBool,
+	"firedamage":                GameruleBool,
+	"freezedamage":              GameruleBool,
+	"functioncommandlimit":      GameruleInt,
+	"keepinventory":             GameruleBool,
+	"maxcommandchainlength":     GameruleInt,
+	"mobgriefing":               GameruleBool,
+	"naturalregeneration":       GameruleBool,
+	"playerssleepingpercentage": GameruleInt,
+	"projectilescanbreakblocks": GameruleBool,
+	"pvp":                       GameruleBool,
+	"randomtickspeed":           GameruleInt,
+	"recipesunlock":             GameruleBool,
+	"respawnblocksexplode":      GameruleBool,
+	"sendcommandfeedback":       GameruleBool,
+	"showbordereffect":          GameruleBool,
+	"showcoordinates":           GameruleBool,
+	"showdaysplayed":            GameruleBool,
+	"showdeathmessages":         GameruleBool,
+	"showrecipemessages":        GameruleBool,
+	"showtags":                  GameruleBool,
+	"spawnradius":               GameruleInt,
+	"tntexplodes":               GameruleBool,
+	"tntexplosiondropdecay":     GameruleBool,
+}
+
+// gamerulePattern 匹配 gamerule 命令输出中的 "name = value"
+var gamerulePattern = regexp.MustCompile(`(\w+) = (true|false|-?\d+)`)
+
+// TimePresets time set 支持的具名时间
+var TimePresets = []string{"day", "night", "noon", "midnight", "sunrise", "sunset"}
+
+// WeatherTypes weather 命令支持的天气
+var WeatherTypes = []string{"clear", "rain", "thunder"}
+
+// parseGamerules 从 gamerule 命令的输出中解析所有规则，布尔值和整数分别转换为 bool 和 int
+func parseGamerules(lines []string) map[string]any {
+	rules := make(map[string]any)
+	for _, line := range lines {
+		for _, match := range gamerulePattern.FindAllStringSubmatch(line, -1) {
+			name, value := match[1], match[2]
+			switch value {
+			case "true", "false":
+				rules[name] = value == "true"
+			default:
+				rules[name], _ = strconv.Atoi(value)
+			}
+		}
+	}
+	return rules
+}
+
+// normalizeGamerule 校验规则名及取值类型，返回可直接用于命令的取值
+func normalizeGamerule(name, value string) (string, error) {
+	kind, ok := Gamerules[strings.ToLower(name)]
+	if !ok {
+		return "", errors.Errorf("unknown gamerule [%s]", name)
+	}
+	value = strings.TrimSpace(value)
+	switch kind {
+	case GameruleBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", errors.Errorf("gamerule [%s] requires a boolean value, got [%s]", name, value)
+		}
+		return strconv.FormatBool(b), nil
+	default:
+		i, err := strconv.Atoi(value)
+		if err != nil || i < 0 {
+			return "", errors.Errorf("gamerule [%s] requires a non-negative integer value, got [%s]", name, value)
+		}
+		return strconv.Itoa(i), nil
+	}
+}
+
+// Gamerules 通过 gamerule 命令读取当前世界的所有游戏规则
+func (server *Server) Gamerules() (map[string]any, error) {
+	lines, err := server.currentProcess().ExecCmdOutput(DefaultCommandWindow, "gamerule")
+	if err != nil {
+		return nil, err
+	}
+	rules := parseGamerules(lines)
+	if len(rules) == 0 {
+		return nil, errors.New("no gamerules found in command output")
+	}
+	return rules, nil
+}
+
+// SetGamerule 设置游戏规则，规则名和取值类型需与 Gamerules 一致
+func (server *Server) SetGamerule(name, value string) error {
+	value, err := normalizeGamerule(name, value)
+	if err != nil {
+		return err
+	}
+	return server.execCmd("gamerule", name, value)
+}
+
+// SetTime 设置世界时间，value 为 TimePresets 中的具名时间或非负的 tick 数
+func (server *Server) SetTime(value string) error {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if !utils.Contains(TimePresets, value) {
+		if i, err := strconv.Atoi(value); err != nil || i < 0 {
+			return errors.Errorf("unsupported time [%s]", value)
+		}
+	}
+	return server.execCmd("time", "set", value)
+}
+
+// SetWeather 设置天气，duration 为持续的秒数，为 0 时由服务器决定
+func (server *Server) SetWeather(weather string, duration int) error {
+	weather = strings.ToLower(strings.TrimSpace(weather))
+	if !utils.Contains(WeatherTypes, weather) {
+		return errors.Errorf("unsupported weather [%s]", weather)
+	}
+	if duration < 0 {
+		return errors.New("duration must not be negative")
+	}
+	arg := []string{"weather", weather}
+	if duration > 0 {
+		arg = append(arg, strconv.Itoa(duration))
+	}
+	return server.execCmd(arg...)
+}
@@ -0,0 +1,90 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseGamerules(t *testing.T) {
+	rules := parseGamerules([]string{
+		"commandBlockOutput = true, doDaylightCycle = false, functionCommandLimit = 10000",
+		"[INFO] unrelated line",
+	})
+	if len(rules) != 3 {
+		t.Fatalf("unexpected rules: %v", rules)
+	}
+	if rules["commandBlockOutput"] != true || rules["doDaylightCycle"] != false || rules["functionCommandLimit"] != 10000 {
+		t.Fatalf("unexpected rules: %v", rules)
+	}
+}
+
+func TestNormalizeGamerule(t *testing.T) {
+	cases := []struct {
+		name, value, expected string
+		ok                    bool
+	}{
+		{"keepInventory", "true", "true", true},
+		{"KEEPINVENTORY", "0", "false", true},
+		{"randomTickSpeed", " 3 ", "3", true},
+		{"randomTickSpeed", "fast", "", false},
+		{"randomTickSpeed", "-1", "", false},
+		{"keepInventory", "yes", "", false},
+		{"noSuchRule", "true", "", false},
+	}
+	for _, c := range cases {
+		value, err := normalizeGamerule(c.name, c.value)
+		if (err == nil) != c.ok || value != c.expected {
+			t.Errorf("normalizeGamerule(%q, %q) = %q, %v", c.name, c.value, value, err)
+		}
+	}
+}
+
+func TestServer_World(t *testing.T) {
+	server := testFakeServer(t)
+	err := server.Start()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	_, err = server.process.WaitReady(5 * time.Second)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	consoleHas := func(text string) bool {
+		for _, line := range server.Console().Lines(0) {
+			if line.Text == text {
+				return true
+			}
+		}
+		return false
+	}
+
+	rules, err := server.Gamerules()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if rules["keepInventory"] != false || rules["randomTickSpeed"] != 1 {
+		t.Fatalf("unexpected rules: %v", rules)
+	}
+
+	if err = server.SetGamerule("keepInventory", "true"); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err = server.SetTime("Day"); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err = server.SetWeather("rain", 600); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	for _, command := range []string{"gamerule keepInventory true", "time set day", "weather rain 600"} {
+		if !consoleHas("[INFO] " + command) {
+			t.Errorf("expected command %q", command)
+		}
+	}
+
+	if err = server.SetTime("dusk"); err == nil {
+		t.Error("unknown time preset should be rejected")
+	}
+	if err = server.SetWeather("snow", 0); err == nil {
+		t.Error("unknown weather should be rejected")
+	}
+}
@@ -1,12 +1,21 @@
 package model
 
+import "github.com/candbright/go-server/internal/mc-server/core"
+
 type ServerInfo struct {
-	ID               string      `json:"id"`
-	Name             string      `json:"name"`
-	Version          string      `json:"version"`
-	Exist            bool        `json:"exist"`
-	Downloading      bool        `json:"downloading"`
-	Active           bool        `json:"active"`
-	ServerProperties interface{} `json:"server_properties"`
-	AllowList        interface{} `json:"allow_list"`
+	ID          string                 `json:"id"`
+	Name        string                 `json:"name"`
+	Version     string                 `json:"version"`
+	Exist       bool                   `json:"exist"`
+	Downloading bool                   `json:"downloading"`
+	Download    *core.DownloadProgress `json:"download,omitempty"`
+	// CreateError 创建服务器时后台下载或应用初始配置失败的原因
+	CreateError      string              `json:"create_error,omitempty"`
+	Active           bool                `json:"active"`
+	Backend          string              `json:"backend"`
+	Status           core.ProcessStatus  `json:"status"`
+	ServerProperties interface{}         `json:"server_properties"`
+	AllowList        interface{}         `json:"allow_list"`
+	Crashes          core.CrashStats     `json:"crashes"`
+	OnlinePlayers    []core.OnlinePlayer `json:"online_players"`
 }
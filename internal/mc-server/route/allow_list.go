@@ -1,8 +1,14 @@
 package route
 
 import (
+	"fmt"
+	"io"
 	"net/http"
+	"path/filepath"
+	"strings"
 
+	"github.com/candbright/go-server/internal/mc-server/core"
+	"github.com/candbright/go-server/internal/mc-server/core/model"
 	"github.com/candbright/go-server/pkg/rest"
 	"github.com/gin-gonic/gin"
 )
@@ -12,15 +18,20 @@ func init() {
 		e.POST("/server/:id/allowlist/get", rest.H(getAllowList))
 		e.POST("/server/:id/allowlist/add", rest.H(addAllowList))
 		e.POST("/server/:id/allowlist/delete", rest.H(deleteAllowList))
+		e.POST("/server/:id/allowlist/import", rest.H(importAllowList))
+		e.POST("/server/:id/allowlist/export", rest.H(exportAllowList))
 	})
 }
 
 type AllowListAddReq struct {
-	Username string `json:"username"`
+	Username string `json:"username" binding:"required"`
+	Xuid     string `json:"xuid"`
+	// IgnoresPlayerLimit 为 true 时该玩家不受服务器人数上限限制
+	IgnoresPlayerLimit bool `json:"ignoresPlayerLimit"`
 }
 
 type AllowListDeleteReq struct {
-	Username string `json:"username"`
+	Username string `json:"username" binding:"required"`
 }
 
 func getAllowList(c *gin.Context) error {
@@ -47,9 +58,13 @@ func addAllowList(c *gin.Context) error {
 	if err != nil {
 		return rest.ErrorWithStatus(http.StatusBadRequest, err)
 	}
-	err = server.AllowListAdd(req.Username)
+	err = server.AllowListAdd(model.AllowListUser{
+		Name:               req.Username,
+		XUid:               req.Xuid,
+		IgnoresPlayerLimit: req.IgnoresPlayerLimit,
+	})
 	if err != nil {
-		return err
+		return cmdError(err)
 	}
 	return nil
 }
@@ -67,7 +82,67 @@ func deleteAllowList(c *gin.Context) error {
 	}
 	err = server.AllowListDelete(req.Username)
 	if err != nil {
-		return err
+		return cmdError(err)
+	}
+	return nil
+}
+
+// importAllowList 从上传的 CSV 或 JSON 文件批量导入白名单，格式按文件扩展名或 format 参数确定，
+// replace=true 时替换整个白名单
+func importAllowList(c *gin.Context) error {
+	id := c.Param("id")
+	server, err := manager.GetServer(id)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusNotFound, err)
+	}
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	defer file.Close()
+	format := c.DefaultQuery("format", strings.TrimPrefix(strings.ToLower(filepath.Ext(header.Filename)), "."))
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	users, err := core.ParseAllowList(data, format)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	added, err := server.AllowListImport(users, c.Query("replace") == "true")
+	if err != nil {
+		return cmdError(err)
+	}
+	return rest.Json(gin.H{
+		"total": len(users),
+		"added": added,
+	})
+}
+
+// exportAllowList 以附件形式导出白名单，format 参数可选 json（默认）或 csv
+func exportAllowList(c *gin.Context) error {
+	id := c.Param("id")
+	server, err := manager.GetServer(id)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusNotFound, err)
+	}
+	allowList := model.AllowList{}
+	if core.Exists(server.AllowListFilePath()) {
+		allowList, err = server.GetAllowList()
+		if err != nil {
+			return err
+		}
+	}
+	format := c.DefaultQuery("format", core.AllowListFormatJson)
+	data, err := core.EncodeAllowList(allowList, format)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	contentType := "application/json"
+	if format == core.AllowListFormatCsv {
+		contentType = "text/csv"
 	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=allowlist-%s.%s", id, format))
+	c.Data(http.StatusOK, contentType, data)
 	return nil
 }
@@ -0,0 +1,120 @@
+package route
+
+import (
+	"net/http"
+
+	"github.com/candbright/go-server/internal/mc-server/core/model"
+	"github.com/candbright/go-server/pkg/rest"
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	registerRoute(func(e *gin.Engine) {
+		e.POST("/allowlist/groups/list", rest.H(listAllowListGroups))
+		e.POST("/allowlist/groups/create", rest.H(createAllowListGroup))
+		e.POST("/allowlist/groups/delete", rest.H(deleteAllowListGroup))
+		e.POST("/allowlist/groups/:name/add", rest.H(addAllowListGroupUser))
+		e.POST("/allowlist/groups/:name/remove", rest.H(removeAllowListGroupUser))
+		e.POST("/allowlist/groups/:name/attach", rest.H(attachAllowListGroup))
+		e.POST("/allowlist/groups/:name/detach", rest.H(detachAllowListGroup))
+	})
+}
+
+type AllowListGroupReq struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type AllowListGroupServerReq struct {
+	ServerID string `json:"server_id" binding:"required"`
+}
+
+func listAllowListGroups(c *gin.Context) error {
+	groups, err := manager.ListAllowListGroups()
+	if err != nil {
+		return err
+	}
+	return rest.Json(groups)
+}
+
+func createAllowListGroup(c *gin.Context) error {
+	var req AllowListGroupReq
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	err = manager.CreateAllowListGroup(req.Name)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	return nil
+}
+
+func deleteAllowListGroup(c *gin.Context) error {
+	var req AllowListGroupReq
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	err = manager.DeleteAllowListGroup(req.Name)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusNotFound, err)
+	}
+	return nil
+}
+
+// addAllowListGroupUser 向分组添加玩家并同步到关联的服务器，failed 中列出同步失败的服务器及原因
+func addAllowListGroupUser(c *gin.Context) error {
+	var req AllowListAddReq
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	result, err := manager.AllowListGroupAdd(c.Param("name"), model.AllowListUser{
+		Name:               req.Username,
+		XUid:               req.Xuid,
+		IgnoresPlayerLimit: req.IgnoresPlayerLimit,
+	})
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	return rest.Json(gin.H{"failed": result})
+}
+
+func removeAllowListGroupUser(c *gin.Context) error {
+	var req AllowListDeleteReq
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	result, err := manager.AllowListGroupRemove(c.Param("name"), req.Username)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	return rest.Json(gin.H{"failed": result})
+}
+
+func attachAllowListGroup(c *gin.Context) error {
+	var req AllowListGroupServerReq
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	result, err := manager.AttachAllowListGroup(c.Param("name"), req.ServerID)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	return rest.Json(gin.H{"failed": result})
+}
+
+func detachAllowListGroup(c *gin.Context) error {
+	var req AllowListGroupServerReq
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	err = manager.DetachAllowListGroup(c.Param("name"), req.ServerID)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	return nil
+}
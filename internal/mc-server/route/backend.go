@@ -0,0 +1,57 @@
+package route
+
+import (
+	"net/http"
+
+	"github.com/candbright/go-server/internal/mc-server/core"
+	"github.com/candbright/go-server/pkg/rest"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registerRoute(func(e *gin.Engine) {
+		e.POST("/server/:id/backend/get", rest.H(getBackend))
+		e.POST("/server/:id/backend/set", rest.H(setBackend))
+	})
+}
+
+type SetBackendReq struct {
+	// Backend 为空时恢复使用全局配置 mc.process.backend
+	Backend string `json:"backend"`
+}
+
+func getBackend(c *gin.Context) error {
+	id := c.Param("id")
+	server, err := manager.GetServer(id)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusNotFound, err)
+	}
+	return rest.Json(gin.H{
+		"backend":   server.Backend(),
+		"active":    server.ProcessBackend(),
+		"supported": core.SupportedBackends(),
+	})
+}
+
+func setBackend(c *gin.Context) error {
+	id := c.Param("id")
+	server, err := manager.GetServer(id)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusNotFound, err)
+	}
+	var req SetBackendReq
+	err = c.ShouldBindJSON(&req)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	if req.Backend != "" {
+		if err = core.ValidateBackend(req.Backend); err != nil {
+			return rest.ErrorWithStatus(http.StatusBadRequest, err)
+		}
+	}
+	if server.Active() {
+		return rest.ErrorWithStatus(http.StatusConflict, errors.New("server is running, stop it before changing backend"))
+	}
+	return server.SetBackend(req.Backend)
+}
@@ -0,0 +1,138 @@
+package route
+
+import (
+	"net/http"
+
+	"github.com/candbright/go-server/internal/mc-server/core"
+	"github.com/candbright/go-server/pkg/rest"
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	registerRoute(func(e *gin.Engine) {
+		e.POST("/server/:id/broadcast", rest.H(broadcast))
+		e.POST("/server/:id/announcements/list", rest.H(listAnnouncements))
+		e.POST("/server/:id/announcements/add", rest.H(addAnnouncement))
+		e.POST("/server/:id/announcements/update", rest.H(updateAnnouncement))
+		e.POST("/server/:id/announcements/delete", rest.H(deleteAnnouncement))
+	})
+}
+
+type BroadcastReq struct {
+	Message string `json:"message" binding:"required"`
+	// Type 发送方式，say（默认）或 tellraw
+	Type string `json:"type"`
+	// Target tellraw 的接收者，玩家名或选择器，默认为 @a
+	Target string `json:"target"`
+}
+
+type AnnouncementReq struct {
+	Messages        []string `json:"messages" binding:"required"`
+	Type            string   `json:"type"`
+	IntervalMinutes int      `json:"interval_minutes"`
+	Cron            string   `json:"cron"`
+	Enabled         *bool    `json:"enabled"`
+}
+
+type AnnouncementUpdateReq struct {
+	ID string `json:"id" binding:"required"`
+	AnnouncementReq
+}
+
+type AnnouncementDeleteReq struct {
+	ID string `json:"id" binding:"required"`
+}
+
+func (req AnnouncementReq) announcement() core.Announcement {
+	// 未指定时默认启用
+	enabled := req.Enabled == nil || *req.Enabled
+	return core.Announcement{
+		Messages:        req.Messages,
+		Type:            req.Type,
+		IntervalMinutes: req.IntervalMinutes,
+		Cron:            req.Cron,
+		Enabled:         enabled,
+	}
+}
+
+func broadcast(c *gin.Context) error {
+	var req BroadcastReq
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	server, err := runningServer(c)
+	if err != nil {
+		return err
+	}
+	err = server.Broadcast(req.Type, req.Message, req.Target)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	return nil
+}
+
+func listAnnouncements(c *gin.Context) error {
+	id := c.Param("id")
+	server, err := manager.GetServer(id)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusNotFound, err)
+	}
+	return rest.Json(server.Announcements().List())
+}
+
+func addAnnouncement(c *gin.Context) error {
+	id := c.Param("id")
+	server, err := manager.GetServer(id)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusNotFound, err)
+	}
+	var req AnnouncementReq
+	err = c.ShouldBindJSON(&req)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	announcement, err := server.Announcements().Add(req.announcement())
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	return rest.Json(announcement)
+}
+
+func updateAnnouncement(c *gin.Context) error {
+	id := c.Param("id")
+	server, err := manager.GetServer(id)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusNotFound, err)
+	}
+	var req AnnouncementUpdateReq
+	err = c.ShouldBindJSON(&req)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	announcement := req.announcement()
+	announcement.ID = req.ID
+	announcement, err = server.Announcements().Update(announcement)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	return rest.Json(announcement)
+}
+
+func deleteAnnouncement(c *gin.Context) error {
+	id := c.Param("id")
+	server, err := manager.GetServer(id)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusNotFound, err)
+	}
+	var req AnnouncementDeleteReq
+	err = c.ShouldBindJSON(&req)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	err = server.Announcements().Delete(req.ID)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusNotFound, err)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package route
+
+import (
+	"net/http"
+
+	"github.com/candbright/go-server/pkg/rest"
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	registerRoute(func(e *gin.Engine) {
+		e.POST("/server/:id/clone", rest.H(cloneServer))
+		e.POST("/server/clone/:job/status", rest.H(cloneStatus))
+	})
+}
+
+type CloneServerReq struct {
+	// Name 新服务器的名称，默认为原名称加 " (copy)"
+	Name string `json:"name"`
+}
+
+// cloneServer 在后台复制服务器，返回的任务 id 可用于查询复制进度
+func cloneServer(c *gin.Context) error {
+	id := c.Param("id")
+	_, err := manager.GetServer(id)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusNotFound, err)
+	}
+	var req CloneServerReq
+	if c.Request.ContentLength > 0 {
+		err = c.ShouldBindJSON(&req)
+		if err != nil {
+			return rest.ErrorWithStatus(http.StatusBadRequest, err)
+		}
+	}
+	job, err := manager.CloneServer(id, req.Name)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	return rest.Json(job)
+}
+
+func cloneStatus(c *gin.Context) error {
+	job, err := manager.CloneJob(c.Param("job"))
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusNotFound, err)
+	}
+	return rest.Json(job)
+}
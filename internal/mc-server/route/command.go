@@ -0,0 +1,64 @@
+package route
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/candbright/go-server/internal/mc-server/core"
+	"github.com/candbright/go-server/pkg/rest"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registerRoute(func(e *gin.Engine) {
+		e.POST("/server/:id/command", rest.H(execCommand))
+	})
+}
+
+type ExecCommandReq struct {
+	Command string `json:"command" binding:"required"`
+	// TimeoutMs 收集命令输出的时间窗口，单位毫秒，默认 1000
+	TimeoutMs int `json:"timeout_ms"`
+}
+
+func execCommand(c *gin.Context) error {
+	id := c.Param("id")
+	server, err := manager.GetServer(id)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusNotFound, err)
+	}
+	var req ExecCommandReq
+	err = c.ShouldBindJSON(&req)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	command := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(req.Command), "/"))
+	if command == "" {
+		return rest.ErrorWithStatus(http.StatusBadRequest, errors.New("command is empty"))
+	}
+	lines, err := server.ExecCmd(time.Duration(req.TimeoutMs)*time.Millisecond, command)
+	var cmdErr core.CommandError
+	if err != nil && !errors.As(err, &cmdErr) {
+		return err
+	}
+	resp := gin.H{
+		"command": command,
+		"lines":   lines,
+		"success": err == nil,
+	}
+	if err != nil {
+		resp["error"] = cmdErr.Line
+	}
+	return rest.Json(resp)
+}
+
+// cmdError 将服务器拒绝执行命令的错误映射为 400，其余错误原样返回
+func cmdError(err error) error {
+	var cmdErr core.CommandError
+	if errors.As(err, &cmdErr) {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	return err
+}
@@ -0,0 +1,89 @@
+package route
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/candbright/go-server/internal/mc-server/core"
+	"github.com/candbright/go-server/pkg/rest"
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	registerRoute(func(e *gin.Engine) {
+		e.POST("/server/:id/console", rest.H(getConsole))
+		e.GET("/server/:id/console", rest.H(streamConsole))
+	})
+}
+
+// consoleBacklog 按查询参数返回控制台缓冲行：指定 since 时返回该序号之后的行，否则返回最近 lines 行
+func consoleBacklog(c *gin.Context, console *core.Console) []core.ConsoleLine {
+	if since, err := strconv.ParseUint(c.Query("since"), 10, 64); err == nil {
+		return console.Since(since)
+	}
+	lines, err := strconv.Atoi(c.DefaultQuery("lines", "100"))
+	if err != nil || lines < 0 {
+		lines = 100
+	}
+	return console.Lines(lines)
+}
+
+func getConsole(c *gin.Context) error {
+	id := c.Param("id")
+	server, err := manager.GetServer(id)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusNotFound, err)
+	}
+	console := server.Console()
+	return rest.Json(gin.H{
+		"last_seq": console.LastSeq(),
+		"lines":    consoleBacklog(c, console),
+	})
+}
+
+// streamConsole 以 SSE 推送控制台输出，先推送缓冲中的历史行，再持续推送新行
+func streamConsole(c *gin.Context) error {
+	id := c.Param("id")
+	server, err := manager.GetServer(id)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusNotFound, err)
+	}
+	console := server.Console()
+	// 先订阅再读取历史，避免两者之间的输出丢失
+	lines, cancel := console.Subscribe()
+	defer cancel()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	var lastSeq uint64
+	for _, line := range consoleBacklog(c, console) {
+		c.SSEvent("line", line)
+		lastSeq = line.Seq
+	}
+	c.Writer.Flush()
+
+	keepalive := time.NewTicker(15 * time.Second)
+	defer keepalive.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				return false
+			}
+			if line.Seq > lastSeq {
+				c.SSEvent("line", line)
+				lastSeq = line.Seq
+			}
+			return true
+		case <-keepalive.C:
+			c.SSEvent("ping", time.Now().Unix())
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+	return nil
+}
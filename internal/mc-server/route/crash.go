@@ -0,0 +1,55 @@
+package route
+
+import (
+	"net/http"
+
+	"github.com/candbright/go-server/pkg/rest"
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	registerRoute(func(e *gin.Engine) {
+		e.POST("/server/:id/restart_policy/get", rest.H(getRestartPolicy))
+		e.POST("/server/:id/restart_policy/set", rest.H(setRestartPolicy))
+		e.POST("/server/:id/crashes/list", rest.H(listCrashes))
+	})
+}
+
+func getRestartPolicy(c *gin.Context) error {
+	id := c.Param("id")
+	server, err := manager.GetServer(id)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusNotFound, err)
+	}
+	return rest.Json(server.RestartPolicy())
+}
+
+func setRestartPolicy(c *gin.Context) error {
+	id := c.Param("id")
+	server, err := manager.GetServer(id)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusNotFound, err)
+	}
+	req := server.RestartPolicy()
+	err = c.ShouldBindJSON(&req)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	err = server.SetRestartPolicy(req)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	return nil
+}
+
+func listCrashes(c *gin.Context) error {
+	id := c.Param("id")
+	server, err := manager.GetServer(id)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusNotFound, err)
+	}
+	return rest.Json(gin.H{
+		"stats":  server.CrashStats(),
+		"events": server.CrashEvents(),
+	})
+}
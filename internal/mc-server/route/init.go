@@ -14,6 +14,28 @@ func Init() {
 		core.ServerManagerConfig{
 			RootDir:      config.Global.Get("mc.path"),
 			LoadInterval: 1 * time.Minute,
+			StopTimeout:  time.Duration(config.Global.GetInt("mc.process.stop_timeout")) * time.Second,
+			Backend:      config.Global.Get("mc.process.backend"),
+			Catalog:      newVersionCatalog(),
 		},
 	)
 }
+
+// newVersionCatalog 按 mc.versions 配置创建版本目录，来源按镜像目录、清单文件、远程地址的顺序合并
+func newVersionCatalog() *core.VersionCatalog {
+	sources := make([]core.VersionSource, 0)
+	if dir := config.Global.Get("mc.versions.mirror"); dir != "" {
+		sources = append(sources, &core.MirrorVersionSource{Dir: dir})
+	}
+	if file := config.Global.Get("mc.versions.manifest"); file != "" {
+		sources = append(sources, &core.ManifestVersionSource{File: file})
+	}
+	if url := config.Global.Get("mc.versions.url"); url != "" || len(sources) == 0 {
+		if url == "" {
+			url = core.DefaultVersionCatalogURL
+		}
+		sources = append(sources, core.NewHTTPVersionSource(url))
+	}
+	ttl := time.Duration(config.Global.GetInt("mc.versions.cache_ttl")) * time.Second
+	return core.NewVersionCatalog(ttl, sources...)
+}
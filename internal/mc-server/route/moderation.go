@@ -0,0 +1,119 @@
+package route
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/candbright/go-server/internal/mc-server/core"
+	"github.com/candbright/go-server/pkg/rest"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registerRoute(func(e *gin.Engine) {
+		e.POST("/server/:id/players/kick", rest.H(kickPlayer))
+		e.POST("/server/:id/bans/list", rest.H(listBans))
+		e.POST("/server/:id/bans/add", rest.H(addBan))
+		e.POST("/server/:id/bans/delete", rest.H(deleteBan))
+	})
+}
+
+type KickReq struct {
+	Name   string `json:"name" binding:"required"`
+	Reason string `json:"reason"`
+}
+
+type BanReq struct {
+	Name   string `json:"name"`
+	Xuid   string `json:"xuid"`
+	Reason string `json:"reason"`
+	// ExpiresAt 临时封禁的到期时间，RFC3339 格式
+	ExpiresAt *time.Time `json:"expires_at"`
+	// DurationMinutes 临时封禁的时长，未指定 ExpiresAt 时使用，两者都为空表示永久封禁
+	DurationMinutes int `json:"duration_minutes"`
+}
+
+type UnbanReq struct {
+	Name string `json:"name"`
+	Xuid string `json:"xuid"`
+}
+
+func kickPlayer(c *gin.Context) error {
+	id := c.Param("id")
+	server, err := manager.GetServer(id)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusNotFound, err)
+	}
+	var req KickReq
+	err = c.ShouldBindJSON(&req)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	err = server.Kick(req.Name, req.Reason)
+	if err != nil {
+		return cmdError(err)
+	}
+	return nil
+}
+
+func listBans(c *gin.Context) error {
+	id := c.Param("id")
+	server, err := manager.GetServer(id)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusNotFound, err)
+	}
+	return rest.Json(server.Bans())
+}
+
+func addBan(c *gin.Context) error {
+	id := c.Param("id")
+	server, err := manager.GetServer(id)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusNotFound, err)
+	}
+	var req BanReq
+	err = c.ShouldBindJSON(&req)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	entry := core.BanEntry{
+		Name:      req.Name,
+		Xuid:      req.Xuid,
+		Reason:    req.Reason,
+		ExpiresAt: req.ExpiresAt,
+	}
+	if entry.ExpiresAt == nil && req.DurationMinutes > 0 {
+		expiresAt := time.Now().Add(time.Duration(req.DurationMinutes) * time.Minute)
+		entry.ExpiresAt = &expiresAt
+	}
+	entry, err = server.Ban(entry)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	return rest.Json(entry)
+}
+
+func deleteBan(c *gin.Context) error {
+	id := c.Param("id")
+	server, err := manager.GetServer(id)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusNotFound, err)
+	}
+	var req UnbanReq
+	err = c.ShouldBindJSON(&req)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	if req.Name == "" && req.Xuid == "" {
+		return rest.ErrorWithStatus(http.StatusBadRequest, errors.New("name or xuid is required"))
+	}
+	removed, err := server.Unban(req.Name, req.Xuid)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return rest.ErrorWithStatus(http.StatusNotFound, errors.New("player is not banned"))
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package route
+
+import (
+	"net/http"
+
+	"github.com/candbright/go-server/internal/mc-server/core"
+	"github.com/candbright/go-server/pkg/rest"
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	registerRoute(func(e *gin.Engine) {
+		e.POST("/server/:id/permissions/get", rest.H(getPermissions))
+		e.POST("/server/:id/permissions/set", rest.H(setPermission))
+		e.POST("/server/:id/permissions/delete", rest.H(deletePermission))
+	})
+}
+
+type PermissionSetReq struct {
+	Xuid string `json:"xuid"`
+	Name string `json:"name"`
+	// Permission 权限等级：operator、member 或 visitor
+	Permission string `json:"permission" binding:"required"`
+}
+
+type PermissionDeleteReq struct {
+	Xuid string `json:"xuid"`
+	Name string `json:"name"`
+}
+
+func getPermissions(c *gin.Context) error {
+	id := c.Param("id")
+	server, err := manager.GetServer(id)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusNotFound, err)
+	}
+	permissions, err := server.GetPermissions()
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusNotFound, err)
+	}
+	return rest.Json(permissions)
+}
+
+func setPermission(c *gin.Context) error {
+	id := c.Param("id")
+	server, err := manager.GetServer(id)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusNotFound, err)
+	}
+	var req PermissionSetReq
+	err = c.ShouldBindJSON(&req)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	err = server.SetPermission(core.PermissionChange{
+		Xuid:       req.Xuid,
+		Name:       req.Name,
+		Permission: req.Permission,
+	})
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	return nil
+}
+
+func deletePermission(c *gin.Context) error {
+	id := c.Param("id")
+	server, err := manager.GetServer(id)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusNotFound, err)
+	}
+	var req PermissionDeleteReq
+	err = c.ShouldBindJSON(&req)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	err = server.DeletePermission(req.Name, req.Xuid)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	return nil
+}
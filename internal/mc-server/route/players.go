@@ -0,0 +1,101 @@
+package route
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/candbright/go-server/internal/mc-server/core"
+	"github.com/candbright/go-server/pkg/rest"
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	registerRoute(func(e *gin.Engine) {
+		e.POST("/server/:id/players/online", rest.H(listOnlinePlayers))
+		e.POST("/server/:id/players/history", rest.H(listPlayerSessions))
+		e.POST("/server/:id/players/leaderboard", rest.H(playtimeLeaderboard))
+		e.POST("/server/:id/players/daily", rest.H(dailyUniquePlayers))
+		e.POST("/players/history", rest.H(listPlayerSessions))
+	})
+}
+
+type PlayerQueryReq struct {
+	// ServerID 仅用于 /players/history，为空时查询所有服务器
+	ServerID string `json:"server_id"`
+	Xuid     string `json:"xuid"`
+	Name     string `json:"name"`
+	// From、To 按进入时间过滤，RFC3339 格式，可省略
+	From  time.Time `json:"from"`
+	To    time.Time `json:"to"`
+	Limit int       `json:"limit"`
+}
+
+// bindPlayerQuery 解析查询条件，请求体可省略；路径中带有服务器 id 时只查询该服务器
+func bindPlayerQuery(c *gin.Context) (core.SessionFilter, int, error) {
+	var req PlayerQueryReq
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			return core.SessionFilter{}, 0, rest.ErrorWithStatus(http.StatusBadRequest, err)
+		}
+	}
+	filter := core.SessionFilter{
+		ServerID: req.ServerID,
+		Xuid:     req.Xuid,
+		Name:     req.Name,
+		From:     req.From,
+		To:       req.To,
+	}
+	if id := c.Param("id"); id != "" {
+		if _, err := manager.GetServer(id); err != nil {
+			return filter, 0, rest.ErrorWithStatus(http.StatusNotFound, err)
+		}
+		filter.ServerID = id
+	}
+	return filter, req.Limit, nil
+}
+
+func listOnlinePlayers(c *gin.Context) error {
+	id := c.Param("id")
+	server, err := manager.GetServer(id)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusNotFound, err)
+	}
+	players := server.OnlinePlayers()
+	return rest.Json(gin.H{
+		"count":   len(players),
+		"players": players,
+	})
+}
+
+func listPlayerSessions(c *gin.Context) error {
+	filter, limit, err := bindPlayerQuery(c)
+	if err != nil {
+		return err
+	}
+	// total 为符合条件的记录总数，不受 limit 影响
+	sessions := manager.PlayerHistory().Sessions(filter, 0)
+	total := len(sessions)
+	if limit > 0 && total > limit {
+		sessions = sessions[:limit]
+	}
+	return rest.Json(gin.H{
+		"total":    total,
+		"sessions": sessions,
+	})
+}
+
+func playtimeLeaderboard(c *gin.Context) error {
+	filter, limit, err := bindPlayerQuery(c)
+	if err != nil {
+		return err
+	}
+	return rest.Json(manager.PlayerHistory().Leaderboard(filter, limit))
+}
+
+func dailyUniquePlayers(c *gin.Context) error {
+	filter, _, err := bindPlayerQuery(c)
+	if err != nil {
+		return err
+	}
+	return rest.Json(manager.PlayerHistory().DailyUniquePlayers(filter))
+}
@@ -0,0 +1,54 @@
+package route
+
+import (
+	"net/http"
+
+	"github.com/candbright/go-server/pkg/rest"
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	registerRoute(func(e *gin.Engine) {
+		e.POST("/server/:id/restart_schedule/get", rest.H(getRestartSchedule))
+		e.POST("/server/:id/restart_schedule/set", rest.H(setRestartSchedule))
+	})
+}
+
+func getRestartSchedule(c *gin.Context) error {
+	id := c.Param("id")
+	server, err := manager.GetServer(id)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusNotFound, err)
+	}
+	schedule, err := server.RestartSchedule()
+	if err != nil {
+		return err
+	}
+	resp := gin.H{"schedule": schedule}
+	if next := server.NextScheduledRestart(); !next.IsZero() {
+		resp["next_restart"] = next
+	}
+	return rest.Json(resp)
+}
+
+// setRestartSchedule 修改定时重启配置，请求中未包含的字段保持原值
+func setRestartSchedule(c *gin.Context) error {
+	id := c.Param("id")
+	server, err := manager.GetServer(id)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusNotFound, err)
+	}
+	req, err := server.RestartSchedule()
+	if err != nil {
+		return err
+	}
+	err = c.ShouldBindJSON(&req)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	schedule, err := server.SetRestartSchedule(req)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	return rest.Json(schedule)
+}
@@ -7,16 +7,21 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"time"
 
 	"github.com/candbright/go-server/internal/mc-server/core"
 	"github.com/candbright/go-server/internal/mc-server/model"
 	"github.com/candbright/go-server/pkg/rest"
 	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
 )
 
 func init() {
 	registerRoute(func(e *gin.Engine) {
 		e.POST("/server/info/list", rest.H(listCurrentServerInfo))
+		e.POST("/server/create", rest.H(createServer))
+		e.POST("/server/:id/rename", rest.H(renameServer))
+		e.POST("/server/:id/delete", rest.H(deleteServer))
 		e.POST("/server/:id/info/get", rest.H(getCurrentServerInfo))
 		e.POST("/server/:id/download_start", rest.H(startDownloadServer))
 		e.POST("/server/:id/start", rest.H(startServer))
@@ -88,17 +93,111 @@ func startDownloadServer(c *gin.Context) error {
 	return manager.DownloadServer(id, "")
 }
 
+type CreateServerReq struct {
+	Name string `json:"name" binding:"required"`
+	// Version 服务器版本，为空时使用最新版本
+	Version string `json:"version"`
+	// Properties 下载完成后写入 server.properties 的初始配置
+	Properties map[string]string `json:"properties"`
+}
+
+type RenameServerReq struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// createServer 创建服务器，服务器文件在后台下载，可通过 info/get 查看下载状态
+func createServer(c *gin.Context) error {
+	var req CreateServerReq
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	server, err := manager.CreateServer(core.CreateServerConfig{
+		Name:       req.Name,
+		Version:    req.Version,
+		Properties: req.Properties,
+	})
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	info, err := transServerInfo(server)
+	if err != nil {
+		return err
+	}
+	return rest.Json(info)
+}
+
+func renameServer(c *gin.Context) error {
+	id := c.Param("id")
+	server, err := manager.GetServer(id)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusNotFound, err)
+	}
+	var req RenameServerReq
+	err = c.ShouldBindJSON(&req)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	err = server.SetServerName(req.Name)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	return nil
+}
+
+func deleteServer(c *gin.Context) error {
+	id := c.Param("id")
+	_, err := manager.GetServer(id)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusNotFound, err)
+	}
+	err = manager.DeleteServer(id)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusConflict, err)
+	}
+	return nil
+}
+
+type StartServerReq struct {
+	// Wait 为 true 时等待服务器完成启动（或启动失败）后再返回
+	Wait bool `json:"wait"`
+	// TimeoutMs 等待启动的最长时间，单位毫秒，默认 120000
+	TimeoutMs int `json:"timeout_ms"`
+}
+
 func startServer(c *gin.Context) error {
 	id := c.Param("id")
 	server, err := manager.GetServer(id)
 	if err != nil {
 		return rest.ErrorWithStatus(http.StatusNotFound, err)
 	}
-	err = server.Start()
+	var req StartServerReq
+	// 请求体可省略，此时不等待启动完成
+	if c.Request.ContentLength > 0 {
+		err = c.ShouldBindJSON(&req)
+		if err != nil {
+			return rest.ErrorWithStatus(http.StatusBadRequest, err)
+		}
+	}
+	if !req.Wait {
+		err = server.Start()
+		if err != nil {
+			return err
+		}
+		return rest.Json(server.Status())
+	}
+	status, err := server.StartAndWait(time.Duration(req.TimeoutMs) * time.Millisecond)
+	var startupErr core.StartupError
+	if errors.As(err, &startupErr) {
+		return rest.ErrorWithStatus(http.StatusInternalServerError, err)
+	}
+	if err != nil && status.State == core.StatusStarting {
+		return rest.ErrorWithStatus(http.StatusGatewayTimeout, err)
+	}
 	if err != nil {
 		return err
 	}
-	return nil
+	return rest.Json(status)
 }
 
 func stopServer(c *gin.Context) error {
@@ -107,11 +206,11 @@ func stopServer(c *gin.Context) error {
 	if err != nil {
 		return rest.ErrorWithStatus(http.StatusNotFound, err)
 	}
-	err = server.Stop()
+	result, err := server.Shutdown()
 	if err != nil {
 		return err
 	}
-	return nil
+	return rest.Json(result)
 }
 
 func uploadServerFile(c *gin.Context) error {
@@ -175,9 +274,20 @@ func transServerInfo(server *core.Server) (model.ServerInfo, error) {
 
 	downloading := server.Downloading()
 	info.Downloading = downloading
+	// 升级时下载的是目标版本
+	version := server.GetVersion()
+	if status, ok := server.UpgradeStatus(); ok && status.State == core.JobRunning {
+		version = status.ToVersion
+	}
+	if progress, ok := manager.DownloadProgress(version); ok && progress.State != core.JobDone {
+		info.Download = &progress
+	}
+	info.CreateError = server.CreateError()
 
 	active := server.Active()
 	info.Active = active
+	info.Backend = server.ProcessBackend()
+	info.Status = server.Status()
 
 	serverProperties, _ := server.ServerProperties()
 	if serverProperties != nil {
@@ -188,6 +298,9 @@ func transServerInfo(server *core.Server) (model.ServerInfo, error) {
 	if allowList != nil {
 		info.AllowList = allowList
 	}
+
+	info.Crashes = server.CrashStats()
+	info.OnlinePlayers = server.OnlinePlayers()
 	return info, nil
 }
 
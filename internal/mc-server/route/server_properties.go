@@ -3,7 +3,6 @@ package route
 import (
 	"net/http"
 
-	"github.com/candbright/go-server/internal/mc-server/utils"
 	"github.com/candbright/go-server/pkg/rest"
 	"github.com/gin-gonic/gin"
 )
@@ -48,11 +47,10 @@ func setServerProperties(c *gin.Context) error {
 	// 获取当前配置
 	currentConfig := serverProperties.GetAll()
 
-	// 将请求中的键转换为小驼峰格式并更新配置
+	// 键名与 server.properties 中一致，只更新非空值
 	for key, value := range *req {
-		if value != "" { // 只更新非空值
-			camelKey := utils.ToCamelCase(key)
-			currentConfig[camelKey] = value
+		if value != "" {
+			currentConfig[key] = value
 		}
 	}
 
@@ -0,0 +1,102 @@
+package route
+
+import (
+	"net/http"
+
+	"github.com/candbright/go-server/pkg/rest"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registerRoute(func(e *gin.Engine) {
+		e.POST("/server/:id/upgrade", rest.H(upgradeServer))
+		e.POST("/server/:id/upgrade/status", rest.H(upgradeStatus))
+		e.POST("/server/:id/versions", rest.H(installedVersions))
+		e.POST("/server/:id/rollback", rest.H(rollbackServer))
+	})
+}
+
+type UpgradeServerReq struct {
+	// Version 目标版本，默认为最新版本
+	Version string `json:"version"`
+	// Wait 为 true 时等待升级完成后返回，否则在后台升级
+	Wait bool `json:"wait"`
+}
+
+// upgradeServer 升级服务器并迁移世界和配置，旧版本目录保留用于回滚
+func upgradeServer(c *gin.Context) error {
+	id := c.Param("id")
+	server, err := manager.GetServer(id)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusNotFound, err)
+	}
+	var req UpgradeServerReq
+	if c.Request.ContentLength > 0 {
+		err = c.ShouldBindJSON(&req)
+		if err != nil {
+			return rest.ErrorWithStatus(http.StatusBadRequest, err)
+		}
+	}
+	if !req.Wait {
+		status, err := manager.StartUpgrade(id, req.Version)
+		if err != nil {
+			return rest.ErrorWithStatus(http.StatusConflict, err)
+		}
+		return rest.Json(status)
+	}
+	err = manager.UpgradeServer(id, req.Version)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusConflict, err)
+	}
+	status, _ := server.UpgradeStatus()
+	return rest.Json(status)
+}
+
+func upgradeStatus(c *gin.Context) error {
+	server, err := manager.GetServer(c.Param("id"))
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusNotFound, err)
+	}
+	status, ok := server.UpgradeStatus()
+	if !ok {
+		return rest.ErrorWithStatus(http.StatusNotFound, errors.Errorf("server [%s] has never been upgraded", c.Param("id")))
+	}
+	return rest.Json(status)
+}
+
+func installedVersions(c *gin.Context) error {
+	server, err := manager.GetServer(c.Param("id"))
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusNotFound, err)
+	}
+	versions, err := server.InstalledVersions()
+	if err != nil {
+		return err
+	}
+	return rest.Json(versions)
+}
+
+type RollbackServerReq struct {
+	Version string `json:"version" binding:"required"`
+	// Force 为 true 时即使世界已被更新的版本打开过也执行回滚
+	Force bool `json:"force"`
+}
+
+// rollbackServer 切换回已安装的旧版本，回滚前备份当前世界数据
+func rollbackServer(c *gin.Context) error {
+	server, err := manager.GetServer(c.Param("id"))
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusNotFound, err)
+	}
+	var req RollbackServerReq
+	err = c.ShouldBindJSON(&req)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	result, err := server.Rollback(req.Version, req.Force)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusConflict, err)
+	}
+	return rest.Json(result)
+}
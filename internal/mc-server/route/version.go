@@ -0,0 +1,147 @@
+package route
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/candbright/go-server/internal/mc-server/core"
+	"github.com/candbright/go-server/pkg/rest"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registerRoute(func(e *gin.Engine) {
+		e.POST("/versions/list", rest.H(listVersions))
+		e.POST("/versions/:version/download", rest.H(preDownloadVersion))
+		e.POST("/versions/:version/download/status", rest.H(downloadStatus))
+		e.POST("/versions/archives/list", rest.H(listArchives))
+		e.POST("/versions/archives/verify", rest.H(verifyArchive))
+		e.POST("/versions/archives/delete", rest.H(deleteArchive))
+		e.POST("/versions/archives/prune", rest.H(pruneArchives))
+	})
+}
+
+type ListVersionsReq struct {
+	// Channel stable 或 preview，为空时返回全部
+	Channel string `json:"channel"`
+	// Refresh 为 true 时忽略缓存重新获取
+	Refresh bool `json:"refresh"`
+}
+
+// listVersions 列出版本目录中的可用版本，从新到旧排序
+func listVersions(c *gin.Context) error {
+	var req ListVersionsReq
+	if c.Request.ContentLength > 0 {
+		err := c.ShouldBindJSON(&req)
+		if err != nil {
+			return rest.ErrorWithStatus(http.StatusBadRequest, err)
+		}
+	}
+	versions, err := manager.VersionCatalog().Versions(c.Request.Context(), req.Channel, req.Refresh)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadGateway, err)
+	}
+	return rest.Json(versions)
+}
+
+// preDownloadVersion 在后台下载版本压缩包，不关联到任何服务器
+func preDownloadVersion(c *gin.Context) error {
+	version := c.Param("version")
+	err := manager.PreDownloadVersion(version)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	return rest.Json(gin.H{
+		"version":    version,
+		"downloaded": manager.ZipExist(version),
+	})
+}
+
+// downloadStatus 返回版本的下载进度；stream=true 时以 SSE 每秒推送一次进度，下载结束后断开
+func downloadStatus(c *gin.Context) error {
+	version := c.Param("version")
+	progress, ok := manager.DownloadProgress(version)
+	if !ok {
+		return rest.ErrorWithStatus(http.StatusNotFound, errors.Errorf("version [%s] is not downloaded", version))
+	}
+	if c.Query("stream") != "true" {
+		return rest.Json(progress)
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.SSEvent("progress", progress)
+	c.Writer.Flush()
+	if progress.State != core.JobRunning {
+		return nil
+	}
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-ticker.C:
+			progress, ok = manager.DownloadProgress(version)
+			if !ok {
+				return false
+			}
+			c.SSEvent("progress", progress)
+			return progress.State == core.JobRunning
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+	return nil
+}
+
+// listArchives 列出缓存的版本压缩包及使用它们的服务器
+func listArchives(c *gin.Context) error {
+	archives, err := manager.ListArchives()
+	if err != nil {
+		return err
+	}
+	return rest.Json(archives)
+}
+
+type ArchiveReq struct {
+	Version string `json:"version" binding:"required"`
+}
+
+// verifyArchive 重新校验压缩包的完整性
+func verifyArchive(c *gin.Context) error {
+	var req ArchiveReq
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	result, err := manager.VerifyArchive(req.Version)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusConflict, err)
+	}
+	return rest.Json(result)
+}
+
+// deleteArchive 删除没有服务器使用的压缩包
+func deleteArchive(c *gin.Context) error {
+	var req ArchiveReq
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	err = manager.DeleteArchive(req.Version)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusConflict, err)
+	}
+	return nil
+}
+
+// pruneArchives 删除所有没有服务器使用的压缩包
+func pruneArchives(c *gin.Context) error {
+	deleted, err := manager.PruneArchives()
+	if err != nil {
+		return err
+	}
+	return rest.Json(gin.H{"deleted": deleted})
+}
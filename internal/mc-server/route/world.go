@@ -0,0 +1,134 @@
+package route
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/candbright/go-server/internal/mc-server/core"
+	"github.com/candbright/go-server/pkg/rest"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registerRoute(func(e *gin.Engine) {
+		e.POST("/server/:id/world/gamerules/list", rest.H(listGamerules))
+		e.POST("/server/:id/world/gamerules/known", rest.H(knownGamerules))
+		e.POST("/server/:id/world/gamerules/set", rest.H(setGamerule))
+		e.POST("/server/:id/world/time/set", rest.H(setTime))
+		e.POST("/server/:id/world/weather/set", rest.H(setWeather))
+	})
+}
+
+type GameruleReq struct {
+	Name string `json:"name" binding:"required"`
+	// Value 布尔或整数，也接受对应的字符串形式
+	Value any `json:"value"`
+}
+
+type TimeReq struct {
+	// Time 具名时间（day、night、noon、midnight、sunrise、sunset）或 tick 数
+	Time any `json:"time"`
+}
+
+type WeatherReq struct {
+	Weather string `json:"weather" binding:"required"`
+	// Duration 持续的秒数，为 0 时由服务器决定
+	Duration int `json:"duration"`
+}
+
+// commandValue 将 JSON 中的取值转换为命令参数。JSON 数字解码为 float64，
+// 直接格式化时大数会变成科学计数法（1e+06），整数值需按整数格式化
+func commandValue(value any) string {
+	if f, ok := value.(float64); ok && f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+		return strconv.FormatInt(int64(f), 10)
+	}
+	return fmt.Sprint(value)
+}
+
+// runningServer 返回正在运行的服务器，服务器不存在返回 404，未运行返回 409
+func runningServer(c *gin.Context) (*core.Server, error) {
+	server, err := manager.GetServer(c.Param("id"))
+	if err != nil {
+		return nil, rest.ErrorWithStatus(http.StatusNotFound, err)
+	}
+	if !server.Active() {
+		return nil, rest.ErrorWithStatus(http.StatusConflict, errors.New("server is not running"))
+	}
+	return server, nil
+}
+
+func listGamerules(c *gin.Context) error {
+	server, err := runningServer(c)
+	if err != nil {
+		return err
+	}
+	rules, err := server.Gamerules()
+	if err != nil {
+		return cmdError(err)
+	}
+	return rest.Json(rules)
+}
+
+func knownGamerules(c *gin.Context) error {
+	return rest.Json(core.Gamerules)
+}
+
+func setGamerule(c *gin.Context) error {
+	var req GameruleReq
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	if req.Value == nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, errors.New("value is required"))
+	}
+	server, err := runningServer(c)
+	if err != nil {
+		return err
+	}
+	err = server.SetGamerule(req.Name, commandValue(req.Value))
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	return nil
+}
+
+func setTime(c *gin.Context) error {
+	var req TimeReq
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	if req.Time == nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, errors.New("time is required"))
+	}
+	server, err := runningServer(c)
+	if err != nil {
+		return err
+	}
+	err = server.SetTime(commandValue(req.Time))
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	return nil
+}
+
+func setWeather(c *gin.Context) error {
+	var req WeatherReq
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	server, err := runningServer(c)
+	if err != nil {
+		return err
+	}
+	err = server.SetWeather(req.Weather, req.Duration)
+	if err != nil {
+		return rest.ErrorWithStatus(http.StatusBadRequest, err)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package route
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/candbright/go-server/internal/mc-server/core"
+	"github.com/gin-gonic/gin"
+)
+
+// fakeServerScript 回显收到的命令的假 bedrock_server
+const fakeServerScript = `#!/bin/sh
+echo "[INFO] Server started."
+while read line; do
+	case "$line" in
+	stop) echo "Quit correctly"; exit 0 ;;
+	*) echo "[INFO] $line" ;;
+	esac
+done
+`
+
+// testServer 在临时目录中创建并启动一个假服务器，返回注册了所有路由的 engine
+func testServer(t *testing.T) (*gin.Engine, *core.Server) {
+	if runtime.GOOS != "linux" {
+		t.Skip("fake bedrock_server requires a posix shell")
+	}
+	rootDir := t.TempDir()
+	workDir := path.Join(rootDir, "server-1", "fake")
+	if err := os.MkdirAll(workDir, os.ModePerm); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	properties, err := os.ReadFile("../core/example/server.properties")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	files := map[string][]byte{
+		path.Join(rootDir, "server-1", "version"): []byte("fake"),
+		path.Join(workDir, "server.properties"):   properties,
+	}
+	for file, data := range files {
+		if err = os.WriteFile(file, data, 0666); err != nil {
+			t.Fatalf("%+v", err)
+		}
+	}
+	if err = os.WriteFile(path.Join(workDir, "bedrock_server"), []byte(fakeServerScript), 0755); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	manager = core.NewServersManager(core.ServerManagerConfig{
+		RootDir: rootDir,
+		Catalog: core.NewVersionCatalog(time.Hour),
+	})
+	server, err := manager.GetServer("1")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err = server.Start(); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	t.Cleanup(func() {
+		if server.Active() {
+			_, _ = server.Shutdown()
+		}
+	})
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	Incubate(engine)
+	return engine, server
+}
+
+func TestSetTimeAndGamerule_LargeNumbers(t *testing.T) {
+	engine, server := testServer(t)
+	tests := []struct {
+		url     string
+		body    string
+		command string
+	}{
+		{url: "/server/1/world/time/set", body: `{"time":1000000}`, command: "[INFO] time set 1000000"},
+		{url: "/server/1/world/gamerules/set", body: `{"name":"randomtickspeed","value":2000000}`, command: "[INFO] gamerule randomtickspeed 2000000"},
+		{url: "/server/1/world/time/set", body: `{"time":"noon"}`, command: "[INFO] time set noon"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, tt.url, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		engine.ServeHTTP(w, req)
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("%s: unexpected status %d: %s", tt.body, w.Code, w.Body.String())
+		}
+		found := false
+		deadline := time.Now().Add(5 * time.Second)
+		for !found && time.Now().Before(deadline) {
+			for _, line := range server.Console().Lines(0) {
+				found = found || line.Text == tt.command
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		if !found {
+			t.Errorf("expected command %q", tt.command)
+		}
+	}
+	if got := commandValue(1.5); got != "1.5" {
+		t.Errorf("fractional values should be kept, got %q", got)
+	}
+}
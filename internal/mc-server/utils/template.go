@@ -120,6 +120,11 @@ func isCommentOrEmpty(line string) bool {
 	return len(trimmed) == 0 || strings.HasPrefix(trimmed, "#")
 }
 
+// TemplateVarName 返回 ConvertToTemplate 为键 key 生成的模板变量名
+func TemplateVarName(key string) string {
+	return toCamelCase(key)
+}
+
 // toCamelCase 转换为小驼峰命名
 func toCamelCase(s string) string {
 	// 统一处理多种分隔符
@@ -1,132 +1,363 @@
 package downloader
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
 
+// ErrDownloading 下载器已有下载在进行
+var ErrDownloading = errors.New("download already in progress")
+
 // DownloadStatus 下载状态
 type DownloadStatus struct {
-	TotalBytes    int64   // 文件总大小
-	Downloaded    int64   // 已下载大小
+	TotalBytes    int64   // 文件总大小，未知时为 0 或 -1
+	Downloaded    int64   // 已下载大小，包含续传前已下载的部分
 	Percentage    float64 // 下载百分比
 	Speed         float64 // 下载速度(KB/s)
 	IsDownloading bool    // 是否正在下载
-	Err           error   // 错误信息
+	Err           error   // 错误信息，取消时为 context.Canceled
+}
+
+// StatusError 服务器返回了非预期的 HTTP 状态码
+type StatusError struct {
+	Code   int
+	Status string
+}
+
+func (e *StatusError) Error() string {
+	return "HTTP error: " + e.Status
+}
+
+// Config 下载器配置，零值字段使用默认值
+type Config struct {
+	// Client 发起请求的 HTTP 客户端，默认为 &http.Client{}
+	Client *http.Client
+	// Retries 失败后的最大重试次数，默认 3，小于 0 时不重试
+	Retries int
+	// Backoff 第一次重试前的等待时间，之后每次翻倍，默认 1 秒
+	Backoff time.Duration
+	// MaxBackoff 重试等待时间的上限，默认 30 秒
+	MaxBackoff time.Duration
 }
 
-// Downloader 下载器
+// Downloader 下载器。下载先写入 <filepath>.part，完成后改名；连接中断时按配置重试，
+// 并通过 HTTP Range 从已下载的部分续传。一次下载结束后可以继续用于下一次下载
 type Downloader struct {
 	client       *http.Client
+	retries      int
+	backoff      time.Duration
+	maxBackoff   time.Duration
 	status       DownloadStatus
 	statusMutex  sync.Mutex
-	stopChan     chan struct{}
 	statusChan   chan DownloadStatus
 	lastUpdate   time.Time
 	lastDownload int64
+	running      bool
+	cancel       context.CancelFunc
 }
 
 // NewDownloader 创建新的下载器
-func NewDownloader() *Downloader {
+func NewDownloader(config ...Config) *Downloader {
+	var cfg Config
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{}
+	}
+	if cfg.Retries == 0 {
+		cfg.Retries = 3
+	}
+	if cfg.Retries < 0 {
+		cfg.Retries = 0
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = time.Second
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 30 * time.Second
+	}
 	return &Downloader{
-		client:     &http.Client{},
-		stopChan:   make(chan struct{}),
+		client:     cfg.Client,
+		retries:    cfg.Retries,
+		backoff:    cfg.Backoff,
+		maxBackoff: cfg.MaxBackoff,
 		statusChan: make(chan DownloadStatus, 10),
 	}
 }
 
-// Download 异步下载文件
-func (d *Downloader) Download(url, filepath string) {
+// Download 异步下载文件，已有下载在进行时不开始新的下载并返回 ErrDownloading
+func (d *Downloader) Download(url, filepath string) error {
+	ctx, ok := d.start(context.Background())
+	if !ok {
+		return ErrDownloading
+	}
 	go func() {
-		defer close(d.statusChan)
+		_ = d.run(ctx, url, filepath)
+	}()
+	return nil
+}
 
-		// 获取文件信息
-		resp, err := d.client.Head(url)
-		if err != nil {
-			d.updateStatus(DownloadStatus{Err: err})
-			return
-		}
-		defer resp.Body.Close()
+// DownloadContext 同步下载文件，ctx 取消或调用 Stop 时中止，已下载的部分保留用于续传
+func (d *Downloader) DownloadContext(ctx context.Context, url, filepath string) error {
+	ctx, ok := d.start(ctx)
+	if !ok {
+		return ErrDownloading
+	}
+	return d.run(ctx, url, filepath)
+}
 
-		if resp.StatusCode != http.StatusOK {
-			d.updateStatus(DownloadStatus{Err: fmt.Errorf("HTTP error: %s", resp.Status)})
-			return
-		}
+// Stop 取消正在进行的下载
+func (d *Downloader) Stop() {
+	d.statusMutex.Lock()
+	defer d.statusMutex.Unlock()
+	if d.cancel != nil {
+		d.cancel()
+	}
+}
 
-		totalBytes := resp.ContentLength
-		d.updateStatus(DownloadStatus{
-			TotalBytes:    totalBytes,
-			IsDownloading: true,
-		})
+// Running 是否有下载在进行
+func (d *Downloader) Running() bool {
+	d.statusMutex.Lock()
+	defer d.statusMutex.Unlock()
+	return d.running
+}
 
-		// 创建目标文件
-		file, err := os.Create(filepath)
-		if err != nil {
-			d.updateStatus(DownloadStatus{Err: err})
-			return
-		}
-		defer file.Close()
+// Status 获取状态通道。通道在下载器的整个生命周期内不会关闭，缓冲满时丢弃最旧的状态
+func (d *Downloader) Status() <-chan DownloadStatus {
+	return d.statusChan
+}
 
-		// 开始下载
-		resp, err = d.client.Get(url)
-		if err != nil {
-			d.updateStatus(DownloadStatus{Err: err})
-			return
-		}
-		defer resp.Body.Close()
+func (d *Downloader) GetCurrentStatus() DownloadStatus {
+	d.statusMutex.Lock()
+	defer d.statusMutex.Unlock()
+	return d.status
+}
 
-		if resp.StatusCode != http.StatusOK {
-			d.updateStatus(DownloadStatus{Err: fmt.Errorf("HTTP error: %s", resp.Status)})
-			return
+// start 标记下载开始，已有下载在进行时返回 false
+func (d *Downloader) start(parent context.Context) (context.Context, bool) {
+	d.statusMutex.Lock()
+	defer d.statusMutex.Unlock()
+	if d.running {
+		return nil, false
+	}
+	ctx, cancel := context.WithCancel(parent)
+	d.running = true
+	d.cancel = cancel
+	d.status = DownloadStatus{IsDownloading: true}
+	d.lastUpdate = time.Now()
+	d.lastDownload = 0
+	return ctx, true
+}
+
+func (d *Downloader) run(ctx context.Context, url, filepath string) error {
+	part := filepath + ".part"
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = d.fetch(ctx, url, part)
+		if err == nil || attempt >= d.retries || !retryable(err) {
+			break
 		}
+		timer := time.NewTimer(d.retryDelay(attempt))
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-timer.C:
+		}
+		timer.Stop()
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = os.Rename(part, filepath)
+	}
 
-		// 创建带缓冲的reader
-		reader := io.TeeReader(resp.Body, &progressWriter{
-			total:      totalBytes,
-			downloader: d,
+	current := d.GetCurrentStatus()
+	if err != nil {
+		d.finish(DownloadStatus{
+			TotalBytes: current.TotalBytes,
+			Downloaded: current.Downloaded,
+			Err:        err,
 		})
+		return err
+	}
+	// 下载完成
+	d.finish(DownloadStatus{
+		TotalBytes:    current.Downloaded,
+		Downloaded:    current.Downloaded,
+		Percentage:    100,
+		IsDownloading: false,
+	})
+	return nil
+}
 
-		// 复制数据到文件
-		_, err = io.Copy(file, reader)
-		if err != nil {
-			d.updateStatus(DownloadStatus{Err: err})
-			return
+// retryDelay 第 attempt 次失败后的等待时间，指数增长且不超过 maxBackoff
+func (d *Downloader) retryDelay(attempt int) time.Duration {
+	delay := d.backoff
+	for i := 0; i < attempt && delay < d.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > d.maxBackoff {
+		delay = d.maxBackoff
+	}
+	return delay
+}
+
+// fetch 下载一次，part 已有内容时请求剩余部分
+func (d *Downloader) fetch(ctx context.Context, url, part string) error {
+	var offset int64
+	if info, err := os.Stat(part); err == nil {
+		offset = info.Size()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	d.resetSpeed(offset)
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	flag := os.O_CREATE | os.O_WRONLY
+	var total int64
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// 服务器不支持续传时从头下载
+		offset = 0
+		total = resp.ContentLength
+		flag |= os.O_TRUNC
+	case http.StatusPartialContent:
+		start, size, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			_ = os.Remove(part)
+			return fmt.Errorf("unexpected Content-Range %q", resp.Header.Get("Content-Range"))
 		}
+		total = size
+		flag |= os.O_APPEND
+	case http.StatusRequestedRangeNotSatisfiable:
+		// 已下载的部分就是完整文件
+		if _, size, ok := parseContentRange(resp.Header.Get("Content-Range")); ok && size == offset {
+			d.updateStatus(DownloadStatus{TotalBytes: size, Downloaded: size, IsDownloading: true})
+			return nil
+		}
+		_ = os.Remove(part)
+		return &StatusError{Code: resp.StatusCode, Status: resp.Status}
+	default:
+		return &StatusError{Code: resp.StatusCode, Status: resp.Status}
+	}
 
-		// 下载完成
-		d.updateStatus(DownloadStatus{
-			TotalBytes:    totalBytes,
-			Downloaded:    totalBytes,
-			Percentage:    100,
-			IsDownloading: false,
-		})
-	}()
+	file, err := os.OpenFile(part, flag, 0666)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// 创建带缓冲的reader
+	reader := io.TeeReader(resp.Body, &progressWriter{
+		total:      total,
+		downloaded: offset,
+		downloader: d,
+	})
+
+	// 复制数据到文件
+	written, err := io.Copy(file, reader)
+	if err != nil {
+		return err
+	}
+	if total > 0 && offset+written < total {
+		return io.ErrUnexpectedEOF
+	}
+	return file.Close()
 }
 
-// Stop 停止下载
-func (d *Downloader) Stop() {
-	close(d.stopChan)
+// parseContentRange 解析 "bytes start-end/size" 或 "bytes */size"，返回起始位置和文件总大小
+func parseContentRange(value string) (int64, int64, bool) {
+	value, ok := strings.CutPrefix(value, "bytes ")
+	if !ok {
+		return 0, 0, false
+	}
+	rng, sizeStr, ok := strings.Cut(value, "/")
+	if !ok {
+		return 0, 0, false
+	}
+	size, err := strconv.ParseInt(sizeStr, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	if rng == "*" {
+		return 0, size, true
+	}
+	startStr, _, ok := strings.Cut(rng, "-")
+	if !ok {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(startStr, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, size, true
 }
 
-// Status 获取状态通道
-func (d *Downloader) Status() <-chan DownloadStatus {
-	return d.statusChan
+// retryable 判断错误是否值得重试：取消、域名不存在和客户端错误不重试
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return false
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		code := statusErr.Code
+		return code >= 500 || code == http.StatusRequestTimeout || code == http.StatusTooManyRequests ||
+			code == http.StatusRequestedRangeNotSatisfiable
+	}
+	return true
 }
 
-func (d *Downloader) GetCurrentStatus() DownloadStatus {
+// resetSpeed 以当前时间和已下载大小作为计算速度的起点
+func (d *Downloader) resetSpeed(downloaded int64) {
 	d.statusMutex.Lock()
 	defer d.statusMutex.Unlock()
-	return d.status
+	d.lastUpdate = time.Now()
+	d.lastDownload = downloaded
 }
 
 func (d *Downloader) updateStatus(status DownloadStatus) {
 	d.statusMutex.Lock()
 	defer d.statusMutex.Unlock()
+	d.setStatus(status)
+}
 
+// finish 记录最终状态并结束下载，与状态更新在同一把锁内完成，Running 与状态保持一致
+func (d *Downloader) finish(status DownloadStatus) {
+	d.statusMutex.Lock()
+	defer d.statusMutex.Unlock()
+	d.running = false
+	if d.cancel != nil {
+		d.cancel()
+		d.cancel = nil
+	}
+	d.setStatus(status)
+}
+
+func (d *Downloader) setStatus(status DownloadStatus) {
 	// 计算下载速度
 	now := time.Now()
 	if !d.lastUpdate.IsZero() {
@@ -145,9 +376,17 @@ func (d *Downloader) updateStatus(status DownloadStatus) {
 	}
 
 	d.status = status
-	select {
-	case d.statusChan <- status:
-	default: // 避免阻塞
+	// 缓冲满时丢弃最旧的状态，保证最新状态（尤其是最终状态）一定能被读取到
+	for {
+		select {
+		case d.statusChan <- status:
+			return
+		default:
+		}
+		select {
+		case <-d.statusChan:
+		default:
+		}
 	}
 }
 
@@ -170,38 +409,3 @@ func (pw *progressWriter) Write(p []byte) (int, error) {
 
 	return n, nil
 }
-
-// 使用示例
-func main() {
-	downloader := NewDownloader()
-
-	// 开始下载
-	url := "https://example.com/largefile.zip"
-	filepath := "largefile.zip"
-	downloader.Download(url, filepath)
-
-	// 实时获取下载状态
-	for status := range downloader.Status() {
-		if status.Err != nil {
-			fmt.Printf("下载错误: %v\n", status.Err)
-			break
-		}
-
-		fmt.Printf("进度: %.2f%%, 速度: %.2f KB/s, 已下载: %d/%d bytes\n",
-			status.Percentage,
-			status.Speed,
-			status.Downloaded,
-			status.TotalBytes)
-
-		if !status.IsDownloading && status.Percentage >= 100 {
-			fmt.Println("下载完成!")
-			break
-		}
-
-		// 模拟中途取消
-		// if status.Percentage > 30 {
-		// 	fmt.Println("取消下载...")
-		// 	downloader.Stop()
-		// }
-	}
-}
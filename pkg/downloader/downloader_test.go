@@ -1,10 +1,16 @@
 package downloader
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -13,7 +19,7 @@ import (
 func createTestServer(content string) *httptest.Server {
 	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "HEAD" {
-			w.Header().Set("Content-Length", string(len(content)))
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
 			return
 		}
 		w.Write([]byte(content))
@@ -248,3 +254,208 @@ func TestDownloader_DownloadSpeed(t *testing.T) {
 		}
 	}
 }
+
+// 创建支持 Range 的测试服务器，前 failures 次请求在发送一半数据后断开连接
+func createFlakyServer(content string, failures int, ranges *[]string) *httptest.Server {
+	var mu sync.Mutex
+	requests := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		n := requests
+		*ranges = append(*ranges, r.Header.Get("Range"))
+		mu.Unlock()
+		if n <= failures {
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.Write([]byte(content[:len(content)/2]))
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(content))
+	}))
+}
+
+func testDownloader() *Downloader {
+	return NewDownloader(Config{Retries: 3, Backoff: 10 * time.Millisecond})
+}
+
+// 测试连接中断后通过 Range 续传
+func TestDownloader_ResumeAfterDisconnect(t *testing.T) {
+	testContent := strings.Repeat("resume content ", 10000)
+	var ranges []string
+	server := createFlakyServer(testContent, 1, &ranges)
+	defer server.Close()
+
+	file := filepath.Join(t.TempDir(), "file.zip")
+	err := testDownloader().DownloadContext(context.Background(), server.URL, file)
+	if err != nil {
+		t.Fatalf("下载失败: %v", err)
+	}
+	fileContent, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("读取下载文件失败: %v", err)
+	}
+	if string(fileContent) != testContent {
+		t.Error("下载文件内容与测试内容不匹配")
+	}
+	if len(ranges) != 2 || ranges[0] != "" || !strings.HasPrefix(ranges[1], "bytes=") || ranges[1] == "bytes=0-" {
+		t.Errorf("期望第二次请求从断点续传, 实际 Range: %q", ranges)
+	}
+	if _, err = os.Stat(file + ".part"); !os.IsNotExist(err) {
+		t.Error("下载完成后 .part 文件应被改名")
+	}
+}
+
+// 测试从已有的 .part 文件续传，以及 .part 已完整时直接完成
+func TestDownloader_ResumeFromPartFile(t *testing.T) {
+	testContent := strings.Repeat("x", 10000)
+	var ranges []string
+	server := createFlakyServer(testContent, 0, &ranges)
+	defer server.Close()
+
+	for _, partSize := range []int{4000, len(testContent)} {
+		ranges = nil
+		file := filepath.Join(t.TempDir(), "file.zip")
+		if err := os.WriteFile(file+".part", []byte(testContent[:partSize]), 0666); err != nil {
+			t.Fatalf("创建 .part 文件失败: %v", err)
+		}
+		downloader := testDownloader()
+		if err := downloader.DownloadContext(context.Background(), server.URL, file); err != nil {
+			t.Fatalf("下载失败: %v", err)
+		}
+		fileContent, _ := os.ReadFile(file)
+		if string(fileContent) != testContent {
+			t.Errorf("已有 %d 字节时下载文件内容不匹配", partSize)
+		}
+		if want := "bytes=" + strconv.Itoa(partSize) + "-"; len(ranges) != 1 || ranges[0] != want {
+			t.Errorf("期望 Range %q, 实际 %q", want, ranges)
+		}
+		if status := downloader.GetCurrentStatus(); status.Percentage != 100 || status.Downloaded != int64(len(testContent)) {
+			t.Errorf("下载完成状态不正确: %+v", status)
+		}
+	}
+}
+
+// 测试服务器错误时按退避重试，客户端错误不重试
+func TestDownloader_Retry(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     int
+		failures int
+		wantErr  bool
+		wantReqs int
+	}{
+		{name: "service unavailable", code: http.StatusServiceUnavailable, failures: 2, wantReqs: 3},
+		{name: "retries exhausted", code: http.StatusBadGateway, failures: 10, wantErr: true, wantReqs: 4},
+		{name: "not found", code: http.StatusNotFound, failures: 10, wantErr: true, wantReqs: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if int(requests.Add(1)) <= tt.failures {
+					w.WriteHeader(tt.code)
+					return
+				}
+				w.Write([]byte("ok"))
+			}))
+			defer server.Close()
+
+			file := filepath.Join(t.TempDir(), "file.zip")
+			err := testDownloader().DownloadContext(context.Background(), server.URL, file)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("期望错误 %v, 实际 %v", tt.wantErr, err)
+			}
+			var statusErr *StatusError
+			if tt.wantErr && (!errors.As(err, &statusErr) || statusErr.Code != tt.code) {
+				t.Errorf("期望 StatusError %d, 实际 %v", tt.code, err)
+			}
+			if int(requests.Load()) != tt.wantReqs {
+				t.Errorf("期望 %d 次请求, 实际 %d", tt.wantReqs, requests.Load())
+			}
+			if _, statErr := os.Stat(file); tt.wantErr != os.IsNotExist(statErr) {
+				t.Errorf("下载失败时不应生成目标文件, 成功时应生成: %v", statErr)
+			}
+		})
+	}
+}
+
+// 测试通过 context 取消下载，已下载的部分保留在 .part 文件中
+func TestDownloader_ContextCancel(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "2000")
+		w.Write([]byte(strings.Repeat("x", 1000)))
+		w.(http.Flusher).Flush()
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	downloader := testDownloader()
+	file := filepath.Join(t.TempDir(), "file.zip")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- downloader.DownloadContext(ctx, server.URL, file)
+	}()
+	for downloader.GetCurrentStatus().Downloaded < 1000 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !downloader.Running() {
+		t.Fatal("下载进行中时 Running 应为 true")
+	}
+	if err := downloader.DownloadContext(context.Background(), server.URL, file); !errors.Is(err, ErrDownloading) {
+		t.Errorf("下载进行中时再次下载应返回 ErrDownloading, 实际 %v", err)
+	}
+	if err := downloader.Download(server.URL, file); !errors.Is(err, ErrDownloading) {
+		t.Errorf("下载进行中时异步下载应返回 ErrDownloading, 实际 %v", err)
+	}
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("期望 context.Canceled, 实际 %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("取消后下载未结束")
+	}
+	if status := downloader.GetCurrentStatus(); !errors.Is(status.Err, context.Canceled) || status.IsDownloading {
+		t.Errorf("取消后状态不正确: %+v", status)
+	}
+	if info, err := os.Stat(file + ".part"); err != nil || info.Size() != 1000 {
+		t.Errorf("取消后应保留已下载的部分: %v", err)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Error("取消后不应生成目标文件")
+	}
+}
+
+// 测试同一个下载器可以多次使用
+func TestDownloader_Reuse(t *testing.T) {
+	server := createTestServer("reuse")
+	defer server.Close()
+
+	downloader := NewDownloader()
+	for i := 0; i < 2; i++ {
+		file := filepath.Join(t.TempDir(), "file.zip")
+		if err := downloader.Download(server.URL, file); err != nil {
+			t.Fatalf("第 %d 次下载未开始: %v", i+1, err)
+		}
+		var finalStatus DownloadStatus
+		for status := range downloader.Status() {
+			if status.Err != nil {
+				t.Fatalf("第 %d 次下载失败: %v", i+1, status.Err)
+			}
+			finalStatus = status
+			if !status.IsDownloading && status.Percentage >= 100 {
+				break
+			}
+		}
+		fileContent, _ := os.ReadFile(file)
+		if string(fileContent) != "reuse" || finalStatus.Percentage != 100 {
+			t.Errorf("第 %d 次下载结果不正确: %q %+v", i+1, fileContent, finalStatus)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package dw
 import (
 	"encoding/json"
 	"encoding/xml"
+	"os"
 
 	"github.com/pelletier/go-toml"
 	"gopkg.in/yaml.v3"
@@ -47,3 +48,16 @@ func Toml[T any](path string) (*DataWriter[T], error) {
 	}
 	return New[T](cfg)
 }
+
+// JsonOrDefault 与 Json 类似，但文件不存在时以 def 作为初始数据，首次调用 Write 时创建文件
+func JsonOrDefault[T any](path string, def T) (*DataWriter[T], error) {
+	cfg := Config{
+		Path:      path,
+		Marshal:   json.Marshal,
+		Unmarshal: json.Unmarshal,
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return &DataWriter[T]{cfg: cfg, Data: def}, nil
+	}
+	return New[T](cfg)
+}
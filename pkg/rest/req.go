@@ -17,6 +17,10 @@ func H(f func(c *gin.Context) error) func(c *gin.Context) {
 	return func(c *gin.Context) {
 		err := f(c)
 		if err == nil {
+			// 处理函数已自行写出响应（如 SSE 推流）时不再追加响应
+			if c.Writer.Written() {
+				return
+			}
 			c.JSON(http.StatusNoContent, nil)
 			return
 		}